@@ -0,0 +1,228 @@
+package corfs
+
+import (
+	"container/list"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/unstoppablemango/ihfs"
+)
+
+// defaultMaxMisses is how many missing paths are remembered by default.
+const defaultMaxMisses = 10000
+
+// listing is a cached directory listing.
+type listing struct {
+	info    ihfs.FileInfo
+	entries []ihfs.DirEntry
+	fetched time.Time
+}
+
+// openDir opens the directory name. With [WithDirCache], its listing is served from
+// memory while it is fresh, and read from the base and the layer otherwise.
+func (f *Fs) openDir(name string) (ihfs.File, error) {
+	if !f.dirCache {
+		return f.openMerged(name)
+	}
+	if l, ok := f.listing(name); ok {
+		return &dirFile{listing: l}, nil
+	}
+
+	file, err := f.openMerged(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	dir, ok := file.(fs.ReadDirFile)
+	if !ok {
+		return nil, &ihfs.PathError{Op: "readdir", Path: name, Err: ihfs.ErrInvalid}
+	}
+	entries, err := dir.ReadDir(-1)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(entries, func(a, b ihfs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+
+	l := &listing{info: info, entries: entries, fetched: time.Now()}
+	f.mu.Lock()
+	f.dirs[name] = l
+	f.mu.Unlock()
+
+	return &dirFile{listing: l}, nil
+}
+
+// listing returns the cached listing of name, if it is fresh.
+func (f *Fs) listing(name string) (*listing, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	l, ok := f.dirs[name]
+	if !ok || (f.cacheTime > 0 && time.Since(l.fetched) >= f.cacheTime) {
+		return nil, false
+	}
+	return l, true
+}
+
+// local describes name, which is not in the layer, from the negative cache and
+// the cached listing of its parent directory. It reports false if neither knows name.
+func (f *Fs) local(op, name string) (ihfs.FileInfo, bool, error) {
+	f.mu.Lock()
+	missed, ok := f.misses.get(name)
+	if ok && time.Since(missed) >= f.negativeTTL {
+		f.misses.remove(name)
+		ok = false
+	}
+	f.mu.Unlock()
+	if ok {
+		return nil, true, &ihfs.PathError{Op: op, Path: name, Err: ihfs.ErrNotExist}
+	}
+
+	if name == "." {
+		return nil, false, nil
+	}
+	l, ok := f.listing(path.Dir(name))
+	if !ok {
+		return nil, false, nil
+	}
+
+	base := path.Base(name)
+	i, found := slices.BinarySearchFunc(l.entries, base, func(e ihfs.DirEntry, name string) int {
+		return strings.Compare(e.Name(), name)
+	})
+	if !found {
+		return nil, true, &ihfs.PathError{Op: op, Path: name, Err: ihfs.ErrNotExist}
+	}
+
+	info, err := l.entries[i].Info()
+	return info, true, err
+}
+
+// missed records that name does not exist in the base, if negative caching is enabled.
+// Expired paths are dropped, and then the oldest ones while [WithMaxMisses] paths are remembered.
+func (f *Fs) missed(name string, err error) {
+	if f.negativeTTL <= 0 || f.maxMisses <= 0 || !isNotExist(err) {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	f.misses.remove(name)
+	for front := f.misses.order.Front(); front != nil; front = f.misses.order.Front() {
+		if f.misses.order.Len() < f.maxMisses && now.Sub(front.Value.(*miss).at) < f.negativeTTL {
+			break
+		}
+		f.misses.remove(front.Value.(*miss).name)
+	}
+	f.misses.add(name, now)
+}
+
+// missSet holds the paths remembered by the negative cache in the order they were missed.
+// Every miss has the same TTL, so the front of the order is always the first to expire.
+type missSet struct {
+	order *list.List
+	names map[string]*list.Element
+}
+
+// miss is a path that was missing from the base at a point in time.
+type miss struct {
+	name string
+	at   time.Time
+}
+
+func newMissSet() *missSet {
+	return &missSet{order: list.New(), names: map[string]*list.Element{}}
+}
+
+// get returns when name was missed, if it is remembered.
+func (s *missSet) get(name string) (time.Time, bool) {
+	e, ok := s.names[name]
+	if !ok {
+		return time.Time{}, false
+	}
+	return e.Value.(*miss).at, true
+}
+
+// add remembers name as missed at. name must not be remembered yet.
+func (s *missSet) add(name string, at time.Time) {
+	s.names[name] = s.order.PushBack(&miss{name: name, at: at})
+}
+
+// remove forgets name, if it is remembered.
+func (s *missSet) remove(name string) {
+	if e, ok := s.names[name]; ok {
+		s.order.Remove(e)
+		delete(s.names, name)
+	}
+}
+
+// forgetDirs drops the cached listings and misses of name, everything beneath
+// it and its ancestors, whose listings include it. f.mu must be held.
+func (f *Fs) forgetDirs(name string) {
+	beneath := func(n string) bool {
+		return name == "." || n == name || strings.HasPrefix(n, name+"/")
+	}
+	for n := range f.misses.names {
+		if beneath(n) {
+			f.misses.remove(n)
+		}
+	}
+	for n := range f.dirs {
+		if beneath(n) {
+			delete(f.dirs, n)
+		}
+	}
+	for dir := name; dir != "."; {
+		dir = path.Dir(dir)
+		delete(f.dirs, dir)
+	}
+}
+
+// dirFile is an open directory served from a cached listing.
+type dirFile struct {
+	listing *listing
+	off     int
+}
+
+// Close implements [fs.File].
+func (d *dirFile) Close() error {
+	return nil
+}
+
+// Read implements [fs.File].
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &ihfs.PathError{Op: "read", Path: d.listing.info.Name(), Err: ihfs.ErrInvalid}
+}
+
+// Stat implements [fs.File].
+func (d *dirFile) Stat() (ihfs.FileInfo, error) {
+	return d.listing.info, nil
+}
+
+// ReadDir implements [fs.ReadDirFile].
+func (d *dirFile) ReadDir(n int) ([]ihfs.DirEntry, error) {
+	entries := d.listing.entries[d.off:]
+	if n <= 0 {
+		d.off += len(entries)
+		return slices.Clone(entries), nil
+	}
+	if len(entries) == 0 {
+		return nil, io.EOF
+	}
+
+	n = min(n, len(entries))
+	d.off += n
+	return slices.Clone(entries[:n]), nil
+}
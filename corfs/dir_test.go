@@ -0,0 +1,258 @@
+package corfs_test
+
+import (
+	"io"
+	"io/fs"
+	"sync"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/corfs"
+	"github.com/unstoppablemango/ihfs/memfs"
+)
+
+// countingFS is a memfs.Fs that counts the Stat and Open calls for each name.
+type countingFS struct {
+	*memfs.Fs
+	mu    sync.Mutex
+	stats map[string]int
+	opens map[string]int
+}
+
+func (c *countingFS) Stat(name string) (ihfs.FileInfo, error) {
+	c.mu.Lock()
+	c.stats[name]++
+	c.mu.Unlock()
+	return c.Fs.Stat(name)
+}
+
+func (c *countingFS) Open(name string) (ihfs.File, error) {
+	c.mu.Lock()
+	c.opens[name]++
+	c.mu.Unlock()
+	return c.Fs.Open(name)
+}
+
+var _ = Describe("Directory and negative caching", func() {
+	var (
+		base  *countingFS
+		layer *memfs.Fs
+	)
+
+	BeforeEach(func() {
+		base = &countingFS{Fs: memfs.New(), stats: map[string]int{}, opens: map[string]int{}}
+		layer = memfs.New()
+		Expect(base.Mkdir("dir", 0o755)).To(Succeed())
+		writeFile(base.Fs, "dir/a.txt", "a")
+		writeFile(base.Fs, "dir/b.txt", "bb")
+	})
+
+	names := func(entries []fs.DirEntry) []string {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		return names
+	}
+
+	Describe("WithDirCache", func() {
+		It("should list directories from memory", func() {
+			cfs := corfs.New(base, layer, corfs.WithDirCache())
+
+			entries, err := fs.ReadDir(cfs, "dir")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(names(entries)).To(Equal([]string{"a.txt", "b.txt"}))
+			entries, err = fs.ReadDir(cfs, "dir")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(names(entries)).To(Equal([]string{"a.txt", "b.txt"}))
+			Expect(base.opens["dir"]).To(Equal(1))
+		})
+
+		It("should list directories from the base every time by default", func() {
+			cfs := corfs.New(base, layer)
+
+			_, err := fs.ReadDir(cfs, "dir")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = fs.ReadDir(cfs, "dir")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(base.opens["dir"]).To(Equal(2))
+		})
+
+		It("should stat files from the listing of their directory", func() {
+			cfs := corfs.New(base, layer, corfs.WithDirCache())
+			_, err := fs.ReadDir(cfs, "dir")
+			Expect(err).NotTo(HaveOccurred())
+
+			info, err := cfs.Stat("dir/b.txt")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Size()).To(Equal(int64(2)))
+			_, err = cfs.Stat("dir/missing.txt")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+
+			Expect(base.stats["dir/b.txt"]).To(BeZero())
+			Expect(base.stats["dir/missing.txt"]).To(BeZero())
+		})
+
+		It("should read directories in batches", func() {
+			cfs := corfs.New(base, layer, corfs.WithDirCache())
+			_, err := fs.ReadDir(cfs, "dir")
+			Expect(err).NotTo(HaveOccurred())
+			f, err := cfs.Open("dir")
+			Expect(err).NotTo(HaveOccurred())
+			dir := f.(fs.ReadDirFile)
+
+			first, err := dir.ReadDir(1)
+			Expect(err).NotTo(HaveOccurred())
+			second, err := dir.ReadDir(1)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = dir.ReadDir(1)
+
+			Expect(err).To(MatchError(io.EOF))
+			Expect(names(append(first, second...))).To(Equal([]string{"a.txt", "b.txt"}))
+		})
+
+		It("should expire listings after the cache time", func() {
+			cfs := corfs.New(base, layer, corfs.WithDirCache(), corfs.WithCacheTime(20*time.Millisecond))
+			_, err := fs.ReadDir(cfs, "dir")
+			Expect(err).NotTo(HaveOccurred())
+			writeFile(base.Fs, "dir/c.txt", "c")
+
+			time.Sleep(30 * time.Millisecond)
+			entries, err := fs.ReadDir(cfs, "dir")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(names(entries)).To(Equal([]string{"a.txt", "b.txt", "c.txt"}))
+		})
+
+		It("should drop listings when files are created", func() {
+			cfs := corfs.New(base, layer, corfs.WithDirCache())
+			_, err := fs.ReadDir(cfs, "dir")
+			Expect(err).NotTo(HaveOccurred())
+
+			f, err := cfs.Create("dir/c.txt")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f.Close()).To(Succeed())
+			entries, err := fs.ReadDir(cfs, "dir")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(names(entries)).To(Equal([]string{"a.txt", "b.txt", "c.txt"}))
+		})
+
+		It("should pass fstest.TestFS", func() {
+			cfs := corfs.New(base, layer, corfs.WithDirCache(), corfs.WithNegativeCache(time.Minute))
+
+			Expect(fstest.TestFS(cfs, "dir", "dir/a.txt", "dir/b.txt")).To(Succeed())
+		})
+	})
+
+	Describe("WithNegativeCache", func() {
+		It("should remember missing paths", func() {
+			cfs := corfs.New(base, layer, corfs.WithNegativeCache(time.Minute))
+
+			_, err := cfs.Stat("missing.txt")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+			_, err = cfs.Open("missing.txt")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+
+			Expect(base.stats["missing.txt"]).To(Equal(1))
+		})
+
+		It("should ask the base every time by default", func() {
+			cfs := corfs.New(base, layer)
+
+			_, err := cfs.Stat("missing.txt")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+			_, err = cfs.Stat("missing.txt")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+
+			Expect(base.stats["missing.txt"]).To(Equal(2))
+		})
+
+		It("should forget missing paths after their TTL", func() {
+			cfs := corfs.New(base, layer, corfs.WithNegativeCache(10*time.Millisecond))
+			_, err := cfs.Stat("missing.txt")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+			writeFile(base.Fs, "missing.txt", "found")
+
+			time.Sleep(20 * time.Millisecond)
+
+			Expect(readFile(cfs, "missing.txt")).To(Equal("found"))
+		})
+
+		It("should forget the oldest missing paths over the limit", func() {
+			cfs := corfs.New(base, layer,
+				corfs.WithNegativeCache(time.Minute),
+				corfs.WithMaxMisses(2),
+			)
+			for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+				_, err := cfs.Stat(name)
+				Expect(err).To(MatchError(fs.ErrNotExist))
+			}
+
+			_, err := cfs.Stat("b.txt")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+			_, err = cfs.Stat("a.txt")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+
+			Expect(base.stats["b.txt"]).To(Equal(1))
+			Expect(base.stats["a.txt"]).To(Equal(2))
+		})
+
+		It("should not remember missing paths with WithMaxMisses(0)", func() {
+			cfs := corfs.New(base, layer,
+				corfs.WithNegativeCache(time.Minute),
+				corfs.WithMaxMisses(0),
+			)
+
+			_, err := cfs.Stat("missing.txt")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+			_, err = cfs.Stat("missing.txt")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+
+			Expect(base.stats["missing.txt"]).To(Equal(2))
+		})
+
+		It("should keep paths that were missed again over older ones", func() {
+			cfs := corfs.New(base, layer,
+				corfs.WithNegativeCache(time.Minute),
+				corfs.WithMaxMisses(2),
+			)
+			for _, name := range []string{"a.txt", "b.txt"} {
+				_, err := cfs.Stat(name)
+				Expect(err).To(MatchError(fs.ErrNotExist))
+			}
+			Expect(cfs.Invalidate("a.txt")).To(Succeed())
+			_, err := cfs.Stat("a.txt")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+
+			_, err = cfs.Stat("c.txt")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+			_, err = cfs.Stat("a.txt")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+			_, err = cfs.Stat("b.txt")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+
+			Expect(base.stats["a.txt"]).To(Equal(2))
+			Expect(base.stats["b.txt"]).To(Equal(2))
+		})
+
+		It("should forget missing paths when they are created", func() {
+			cfs := corfs.New(base, layer, corfs.WithNegativeCache(time.Minute))
+			_, err := cfs.Stat("missing.txt")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+
+			f, err := cfs.Create("missing.txt")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f.Close()).To(Succeed())
+
+			_, err = cfs.Stat("missing.txt")
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
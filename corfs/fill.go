@@ -0,0 +1,108 @@
+package corfs
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"path"
+	"slices"
+
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/try"
+	"github.com/unstoppablemango/ihfs/union"
+)
+
+// fill is a copy of a file from the base to the layer that is in progress.
+type fill struct {
+	done chan struct{}
+	err  error
+}
+
+// fill copies name, described by bfi, from the base to the layer and records it in the
+// index with version. Concurrent fills of the same name wait for the first one to finish
+// and share its result instead of copying the file again.
+func (f *Fs) fill(name string, bfi ihfs.FileInfo, version string) error {
+	f.mu.Lock()
+	if c, ok := f.fills[name]; ok {
+		f.mu.Unlock()
+		<-c.done
+		return c.err
+	}
+	c := &fill{done: make(chan struct{})}
+	f.fills[name] = c
+	f.mu.Unlock()
+
+	c.err = f.copyToLayer(name)
+	if c.err == nil {
+		f.filled(name, bfi.Size(), version)
+	}
+
+	f.mu.Lock()
+	delete(f.fills, name)
+	f.mu.Unlock()
+	close(c.done)
+
+	return c.err
+}
+
+// stagingDir is the hidden directory in the root of the layer that files are
+// copied to before they are renamed into place. It is left out of listings.
+const stagingDir = ".corfs-staging"
+
+// copyToLayer copies a file from the base to the layer. When the layer supports
+// renames, the file is copied to the staging directory first, so that readers
+// never see a partial copy.
+func (f *Fs) copyToLayer(name string) error {
+	if _, ok := f.layer.(ihfs.RenameFS); !ok {
+		return union.CopyToLayer(f.base, f.layer, name)
+	}
+
+	if dir := path.Dir(name); dir != "." {
+		if err := try.MkdirAll(f.layer, dir, 0o777); err != nil {
+			return err
+		}
+	}
+
+	tmp := tempName(name)
+	if err := union.CopyToLayerAs(f.base, f.layer, name, tmp); err != nil {
+		return err
+	}
+
+	err := try.Rename(f.layer, tmp, name)
+	if errors.Is(err, ihfs.ErrExist) {
+		// Not every file system replaces existing files when renaming
+		if err = try.Remove(f.layer, name); err == nil || isNotExist(err) {
+			err = try.Rename(f.layer, tmp, name)
+		}
+	}
+	if err != nil {
+		_ = try.Remove(f.layer, tmp)
+		return err
+	}
+
+	return nil
+}
+
+// tempName returns a unique name in the staging directory to copy name to.
+func tempName(name string) string {
+	return path.Join(stagingDir, fmt.Sprintf("%x-%s", rand.Uint64(), path.Base(name)))
+}
+
+// clearStaging removes copies left in the staging directory by fills that
+// never finished, such as those of a process that died mid-copy.
+func (f *Fs) clearStaging() {
+	if _, ok := f.layer.(ihfs.RenameFS); ok {
+		_ = try.RemoveAll(f.layer, stagingDir)
+	}
+}
+
+// stagingHidden is the root directory of the layer, without the staging directory.
+type stagingHidden struct{ ihfs.File }
+
+// ReadDir implements [fs.ReadDirFile].
+func (d stagingHidden) ReadDir(n int) ([]ihfs.DirEntry, error) {
+	entries, err := try.ReadDirFile(d.File, n)
+	return slices.DeleteFunc(entries, func(e ihfs.DirEntry) bool {
+		return e.Name() == stagingDir
+	}), err
+}
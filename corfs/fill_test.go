@@ -0,0 +1,197 @@
+package corfs_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/corfs"
+	"github.com/unstoppablemango/ihfs/memfs"
+)
+
+// gatedFS is a memfs.Fs whose files block halfway through reading until gate is closed.
+type gatedFS struct {
+	*memfs.Fs
+	gate  chan struct{}
+	stats atomic.Int32
+	opens atomic.Int32
+	err   error
+}
+
+func (g *gatedFS) Stat(name string) (ihfs.FileInfo, error) {
+	g.stats.Add(1)
+	return g.Fs.Stat(name)
+}
+
+func (g *gatedFS) Open(name string) (ihfs.File, error) {
+	g.opens.Add(1)
+	if g.err != nil {
+		<-g.gate
+		return nil, g.err
+	}
+	f, err := g.Fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &gatedFile{File: f, gate: g.gate}, nil
+}
+
+type gatedFile struct {
+	ihfs.File
+	gate chan struct{}
+	read bool
+}
+
+func (f *gatedFile) Read(p []byte) (int, error) {
+	if f.read {
+		<-f.gate
+	}
+	f.read = true
+	return f.File.Read(p[:min(len(p), 4)])
+}
+
+var _ = Describe("Fills", func() {
+	var (
+		base  *gatedFS
+		layer *memfs.Fs
+		cfs   *corfs.Fs
+	)
+
+	BeforeEach(func() {
+		base = &gatedFS{Fs: memfs.New(), gate: make(chan struct{})}
+		layer = memfs.New()
+		Expect(base.Mkdir("dir", 0o755)).To(Succeed())
+		writeFile(base.Fs, "dir/file.txt", "complete content")
+		cfs = corfs.New(base, layer)
+	})
+
+	// readAll reads dir/file.txt from n goroutines once they are all waiting on the same fill.
+	readAll := func(n int) ([]string, []error) {
+		var (
+			wg       sync.WaitGroup
+			contents = make([]string, n)
+			errs     = make([]error, n)
+		)
+		for i := range n {
+			wg.Go(func() {
+				defer GinkgoRecover()
+				data, err := fs.ReadFile(cfs, "dir/file.txt")
+				contents[i], errs[i] = string(data), err
+			})
+		}
+
+		Eventually(base.stats.Load).Should(BeEquivalentTo(n))
+		time.Sleep(10 * time.Millisecond)
+		close(base.gate)
+		wg.Wait()
+		return contents, errs
+	}
+
+	It("should copy a file once for concurrent reads", func() {
+		contents, errs := readAll(8)
+
+		Expect(errs).To(HaveEach(Succeed()))
+		Expect(contents).To(HaveEach("complete content"))
+		Expect(base.opens.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("should share fill errors", func() {
+		base.err = errors.New("base unavailable")
+
+		_, errs := readAll(4)
+
+		Expect(errs).To(HaveEach(MatchError("base unavailable")))
+		Expect(base.opens.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("should copy to a staging directory", func() {
+		done := make(chan error)
+		go func() {
+			_, err := fs.ReadFile(cfs, "dir/file.txt")
+			done <- err
+		}()
+
+		Eventually(func() ([]ihfs.DirEntry, error) {
+			return fs.ReadDir(layer, ".corfs-staging")
+		}).Should(HaveLen(1))
+		entries, err := fs.ReadDir(layer, "dir")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+
+		close(base.gate)
+		Eventually(done).Should(Receive(BeNil()))
+		data, err := fs.ReadFile(layer, "dir/file.txt")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("complete content"))
+		entries, err = fs.ReadDir(layer, ".corfs-staging")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("should hide the staging directory from listings", func() {
+		go func() { _, _ = fs.ReadFile(cfs, "dir/file.txt") }()
+		Eventually(func() ([]ihfs.DirEntry, error) {
+			return fs.ReadDir(layer, ".corfs-staging")
+		}).Should(HaveLen(1))
+
+		entries, err := fs.ReadDir(cfs, ".")
+
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		Expect(names).To(ConsistOf("dir"))
+		close(base.gate)
+	})
+
+	It("should clear copies left in the staging directory", func() {
+		Expect(layer.Mkdir(".corfs-staging", 0o755)).To(Succeed())
+		writeFile(layer, ".corfs-staging/1234-file.txt", "partial")
+
+		corfs.New(base, layer)
+
+		_, err := layer.Stat(".corfs-staging")
+		Expect(err).To(MatchError(fs.ErrNotExist))
+	})
+
+	It("should remove the temporary file when the copy fails", func() {
+		var failing failingFS
+		failing.Fs = base.Fs
+		cfs := corfs.New(failing, layer)
+
+		_, err := cfs.Open("dir/file.txt")
+
+		Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+		entries, err := fs.ReadDir(layer, "dir")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+		entries, err = fs.ReadDir(layer, ".corfs-staging")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+})
+
+// failingFS is a memfs.Fs whose files fail to read.
+type failingFS struct{ *memfs.Fs }
+
+func (f failingFS) Open(name string) (ihfs.File, error) {
+	file, err := f.Fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return failingFile{file}, nil
+}
+
+type failingFile struct{ ihfs.File }
+
+func (failingFile) Read([]byte) (int, error) {
+	return 0, io.ErrUnexpectedEOF
+}
@@ -2,6 +2,8 @@ package corfs
 
 import (
 	"errors"
+	"io/fs"
+	"sync"
 	"syscall"
 	"time"
 
@@ -18,18 +20,59 @@ import (
 // If the cache duration is 0, cache time will be unlimited, i.e. once
 // a file is in the layer, the base will never be read again for this file.
 //
-// For cache times greater than 0, the modification time of a file is
-// checked. Note that a lot of file system implementations only allow a
-// resolution of a second for timestamps.
+// For cache times greater than 0, a cached file is validated against the base
+// once the cache time has passed since it was copied or last validated, and the
+// modification time of the base file is compared to that of the cached copy.
+// Note that a lot of file system implementations only allow a resolution of a
+// second for timestamps. A [Validator] can compare sizes,
+// content hashes or the versions reported by the base instead.
+//
+// The files copied from the base are recorded in an index, which can be persisted
+// with [WithIndex]. With [WithMaxBytes] or [WithMaxEntries], they are evicted from
+// the layer by their use, as recorded in the index. Files that were written to the
+// layer directly are not recorded, and are never evicted.
+//
+// With [WithDirCache] and [WithNegativeCache], directory listings and paths
+// missing from the base are remembered, so that Stat and ReadDir can be
+// answered without asking the base.
 //
 // The implementation is based heavily on [afero.CacheOnReadFs].
 type Fs struct {
 	base      ihfs.FS
 	layer     ihfs.FS
 	cacheTime time.Duration
+	remove    RemovePolicy
+	validator Validator
 	fopts     []union.Option
+
+	maxBytes   int64
+	maxEntries int
+	eviction   EvictionPolicy
+	indexFS    ihfs.FS
+	indexName  string
+
+	dirCache    bool
+	negativeTTL time.Duration
+	maxMisses   int
+
+	mu     sync.Mutex
+	saving sync.Mutex
+	index  *index
+	fills  map[string]*fill
+	dirs   map[string]*listing
+	misses *missSet
 }
 
+// RemovePolicy controls what [Fs.Remove] and [Fs.RemoveAll] remove.
+type RemovePolicy int
+
+const (
+	// RemoveBoth removes the file from the base and invalidates the cached copy.
+	RemoveBoth RemovePolicy = iota
+	// RemoveCached only invalidates the cached copy, leaving the base untouched.
+	RemoveCached
+)
+
 // New creates a new cache-on-read filesystem with the given base and layer.
 // The cacheTime parameter determines how long cached files are valid.
 // If cacheTime is 0, files are cached indefinitely.
@@ -38,8 +81,14 @@ func New(base, layer ihfs.FS, options ...Option) *Fs {
 		base:      base,
 		layer:     layer,
 		cacheTime: 0,
+		maxMisses: defaultMaxMisses,
+		fills:     map[string]*fill{},
+		dirs:      map[string]*listing{},
+		misses:    newMissSet(),
 	}
 	fopt.ApplyAll(f, options)
+	f.loadIndex()
+	f.clearStaging()
 
 	return f
 }
@@ -59,36 +108,40 @@ const (
 	cacheLocal
 )
 
-// cacheStatus checks the cache status of a file
-func (f *Fs) cacheStatus(name string) (state cacheState, fi ihfs.FileInfo, err error) {
+// cacheStatus checks the cache status of a file. For stale files, it also
+// returns the version of the base file when a [Validator] is configured.
+func (f *Fs) cacheStatus(name string) (state cacheState, fi ihfs.FileInfo, version string, err error) {
 	var lfi, bfi ihfs.FileInfo
 	lfi, err = try.Stat(f.layer, name)
 	if err == nil {
 		if f.cacheTime == 0 {
-			return cacheHit, lfi, nil
+			return cacheHit, lfi, "", nil
 		}
-		if lfi.ModTime().Add(f.cacheTime).Before(time.Now()) {
+		if f.checked(name, lfi).Add(f.cacheTime).Before(time.Now()) {
 			bfi, err = try.Stat(f.base, name)
 			if err != nil {
-				return cacheLocal, lfi, nil
+				return cacheLocal, lfi, "", nil
 			}
-			if bfi.ModTime().After(lfi.ModTime()) {
-				return cacheStale, bfi, nil
+			if bfi.IsDir() {
+				return cacheHit, lfi, "", nil
+			}
+			stale, version, err := f.stale(name, lfi, bfi)
+			if err != nil {
+				return cacheMiss, nil, "", err
 			}
+			if stale {
+				return cacheStale, bfi, version, nil
+			}
+			f.validated(name, lfi)
 		}
-		return cacheHit, lfi, nil
+		return cacheHit, lfi, "", nil
 	}
 
-	if errors.Is(err, ihfs.ErrNotExist) || errors.Is(err, syscall.ENOENT) {
-		return cacheMiss, nil, nil
+	if isNotExist(err) {
+		return cacheMiss, nil, "", nil
 	}
 
-	return cacheMiss, nil, err
-}
-
-// copyToLayer copies a file from the base to the layer
-func (f *Fs) copyToLayer(name string) error {
-	return union.CopyToLayer(f.base, f.layer, name)
+	return cacheMiss, nil, "", err
 }
 
 // Base implements [ihfs.Decorator].
@@ -103,7 +156,7 @@ func (f *Fs) Name() string {
 
 // Open implements [fs.FS].
 func (f *Fs) Open(name string) (ihfs.File, error) {
-	status, fi, err := f.cacheStatus(name)
+	status, fi, version, err := f.cacheStatus(name)
 	if err != nil {
 		return nil, err
 	}
@@ -113,30 +166,41 @@ func (f *Fs) Open(name string) (ihfs.File, error) {
 		return f.layer.Open(name)
 
 	case cacheMiss:
-		bfi, err := try.Stat(f.base, name)
+		bfi, err := f.statBase("open", name)
 		if err != nil {
 			return nil, err
 		}
 		if !bfi.IsDir() {
-			if err := f.copyToLayer(name); err != nil {
+			version, err := f.baseVersion(name, bfi)
+			if err != nil {
+				return nil, err
+			}
+			if err := f.fill(name, bfi, version); err != nil {
 				return nil, err
 			}
 			return f.layer.Open(name)
 		}
 	case cacheStale:
 		if !fi.IsDir() {
-			if err := f.copyToLayer(name); err != nil {
+			if err := f.fill(name, fi, version); err != nil {
 				return nil, err
 			}
 			return f.layer.Open(name)
 		}
 	case cacheHit:
 		if !fi.IsDir() {
+			f.hit(name)
 			return f.layer.Open(name)
 		}
 	}
 
 	// the dirs from cacheHit, cacheStale, and cacheMiss fall down here:
+	return f.openDir(name)
+}
+
+// openMerged opens the directory name in the base and in the layer,
+// merging their entries.
+func (f *Fs) openMerged(name string) (ihfs.File, error) {
 	bfile, bErr := f.base.Open(name)
 	lfile, lErr := f.layer.Open(name)
 
@@ -148,5 +212,143 @@ func (f *Fs) Open(name string) (ihfs.File, error) {
 	if lErr != nil && bfile == nil {
 		return nil, lErr
 	}
+	if _, ok := lfile.(fs.ReadDirFile); ok && name == "." {
+		lfile = stagingHidden{lfile}
+	}
 	return union.NewFile(bfile, lfile, f.fopts...), nil
 }
+
+// Stat implements [ihfs.StatFS]. Cached files are described from the layer,
+// other files from the base without copying them to the layer.
+func (f *Fs) Stat(name string) (ihfs.FileInfo, error) {
+	status, fi, _, err := f.cacheStatus(name)
+	if err != nil {
+		return nil, err
+	}
+
+	switch status {
+	case cacheHit:
+		if !fi.IsDir() {
+			f.hit(name)
+		}
+		return fi, nil
+	case cacheLocal:
+		return fi, nil
+	case cacheStale:
+		return fi, nil
+	default:
+		return f.statBase("stat", name)
+	}
+}
+
+// statBase describes name, which is not in the layer, from the cached directory
+// listings and misses if possible, and from the base otherwise.
+func (f *Fs) statBase(op, name string) (ihfs.FileInfo, error) {
+	if fi, ok, err := f.local(op, name); ok {
+		return fi, err
+	}
+
+	fi, err := try.Stat(f.base, name)
+	if err != nil {
+		f.missed(name, err)
+		return nil, err
+	}
+	return fi, nil
+}
+
+// Create implements [ihfs.CreateFS]. The file is created in the base and the
+// cached copy is invalidated. Writes go to the base, and the layer is refreshed
+// from the base when the file is closed.
+func (f *Fs) Create(name string) (ihfs.File, error) {
+	bfile, err := try.Create(f.base, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := f.Invalidate(name); err != nil {
+		_ = bfile.Close()
+		return nil, err
+	}
+
+	return &writer{File: bfile, fsys: f, name: name}, nil
+}
+
+// WriteFile implements [ihfs.WriteFileFS]. The data is written to the base
+// and the cached copy is refreshed from it.
+func (f *Fs) WriteFile(name string, data []byte, perm ihfs.FileMode) error {
+	if err := try.WriteFile(f.base, name, data, perm); err != nil {
+		return err
+	}
+
+	return f.refresh(name)
+}
+
+// Remove implements [ihfs.RemoveFS]. Depending on the [RemovePolicy], the
+// file is removed from the base, and the cached copy is removed from the layer.
+// With [RemoveBoth], a file that only exists on one side is removed from it,
+// and an error is only returned if it exists on neither.
+func (f *Fs) Remove(name string) error {
+	var baseErr error
+	if f.remove == RemoveBoth {
+		baseErr = try.Remove(f.base, name)
+		if baseErr != nil && !isNotExist(baseErr) {
+			return baseErr
+		}
+	}
+
+	f.forget(name)
+	err := try.Remove(f.layer, name)
+	if err != nil && !isNotExist(err) {
+		return err
+	}
+	if err != nil {
+		return baseErr
+	}
+
+	return nil
+}
+
+// RemoveAll implements [ihfs.RemoveAllFS]. Depending on the [RemovePolicy],
+// the tree is removed from the base, and the cached copy is removed from the layer.
+func (f *Fs) RemoveAll(name string) error {
+	if f.remove == RemoveBoth {
+		if err := try.RemoveAll(f.base, name); err != nil {
+			return err
+		}
+	}
+
+	return f.Invalidate(name)
+}
+
+// Invalidate removes the cached copy of name, and anything cached beneath it,
+// from the layer. The next read of name goes to the base.
+func (f *Fs) Invalidate(name string) error {
+	f.forget(name)
+	return try.RemoveAll(f.layer, name)
+}
+
+// Purge removes everything cached in the layer.
+func (f *Fs) Purge() error {
+	f.forget(".")
+
+	entries, err := fs.ReadDir(f.layer, ".")
+	if err != nil {
+		if isNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var errs []error
+	for _, e := range entries {
+		if err := f.Invalidate(e.Name()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func isNotExist(err error) bool {
+	return errors.Is(err, ihfs.ErrNotExist) || errors.Is(err, syscall.ENOENT)
+}
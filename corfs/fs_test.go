@@ -33,6 +33,36 @@ func (m minimalFSWithMkdirAll) MkdirAll(name string, perm ihfs.FileMode) error {
 	return nil
 }
 
+// writeFileFS adds WriteFile to a memfs.Fs.
+type writeFileFS struct{ *memfs.Fs }
+
+func (m writeFileFS) WriteFile(name string, data []byte, perm ihfs.FileMode) error {
+	f, err := m.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err = f.(io.Writer).Write(data); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func writeFile(fsys *memfs.Fs, name, content string) {
+	GinkgoHelper()
+	f, err := fsys.Create(name)
+	Expect(err).NotTo(HaveOccurred())
+	_, err = f.(io.Writer).Write([]byte(content))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(f.Close()).To(Succeed())
+}
+
+func readFile(fsys ihfs.FS, name string) string {
+	GinkgoHelper()
+	data, err := fs.ReadFile(fsys, name)
+	Expect(err).NotTo(HaveOccurred())
+	return string(data)
+}
+
 var _ = Describe("Fs", func() {
 	It("should return the base filesystem", func() {
 		fsys := &testfs.BoringFs{}
@@ -84,7 +114,10 @@ var _ = Describe("Fs", func() {
 				},
 			}
 
-			var fileCreated bool
+			var (
+				fileCreated bool
+				renamed     string
+			)
 			layer := testfs.New(
 				testfs.WithStat(func(name string) (ihfs.FileInfo, error) {
 					if fileCreated {
@@ -102,6 +135,10 @@ var _ = Describe("Fs", func() {
 					fileCreated = true
 					return layerFile, nil
 				}),
+				testfs.WithRename(func(oldpath, newpath string) error {
+					renamed = newpath
+					return nil
+				}),
 				testfs.WithChtimes(func(name string, atime, mtime time.Time) error {
 					return nil
 				}),
@@ -121,6 +158,7 @@ var _ = Describe("Fs", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(file).ToNot(BeNil())
 			Expect(fileCreated).To(BeTrue(), "file should have been cached to layer")
+			Expect(renamed).To(Equal("test.txt"), "file should have been renamed into place")
 		})
 
 		It("should read from cache on subsequent reads", func() {
@@ -1382,7 +1420,10 @@ var _ = Describe("Fs", func() {
 				},
 			}
 
-			var fileCreated bool
+			var (
+				fileCreated bool
+				renamed     string
+			)
 			layer := testfs.New(
 				testfs.WithStat(func(name string) (ihfs.FileInfo, error) {
 					if !fileCreated {
@@ -1406,6 +1447,10 @@ var _ = Describe("Fs", func() {
 					fileCreated = true
 					return layerFile, nil
 				}),
+				testfs.WithRename(func(oldpath, newpath string) error {
+					renamed = newpath
+					return nil
+				}),
 				testfs.WithChtimes(func(name string, atime, mtime time.Time) error {
 					return nil
 				}),
@@ -1418,6 +1463,7 @@ var _ = Describe("Fs", func() {
 			file, err := cfs.Open("test.txt")
 			Expect(err).ToNot(HaveOccurred())
 			Expect(file).ToNot(BeNil())
+			Expect(renamed).To(Equal("test.txt"))
 		})
 
 		It("should handle cacheStale for directory", func() {
@@ -1495,6 +1541,235 @@ var _ = Describe("Fs", func() {
 		})
 	})
 
+	Describe("writes", func() {
+		var base, layer *memfs.Fs
+
+		BeforeEach(func() {
+			base, layer = memfs.New(), memfs.New()
+			Expect(base.Mkdir("dir", 0o755)).To(Succeed())
+			writeFile(base, "dir/file.txt", "base content")
+		})
+
+		It("should create files in the base and the layer", func() {
+			cfs := corfs.New(base, layer)
+
+			f, err := cfs.Create("dir/new.txt")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = f.(io.Writer).Write([]byte("created"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f.Close()).To(Succeed())
+
+			Expect(readFile(base, "dir/new.txt")).To(Equal("created"))
+			Expect(readFile(layer, "dir/new.txt")).To(Equal("created"))
+		})
+
+		It("should write through to the base when the layer fails", func() {
+			layer := testfs.New(
+				testfs.WithMkdirAll(func(string, ihfs.FileMode) error {
+					return errors.New("read-only")
+				}),
+				testfs.WithRemoveAll(func(string) error { return nil }),
+			)
+			cfs := corfs.New(base, layer)
+
+			f, err := cfs.Create("dir/new.txt")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = f.(io.Writer).Write([]byte("created"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f.Close()).To(Succeed())
+
+			Expect(readFile(base, "dir/new.txt")).To(Equal("created"))
+		})
+
+		It("should refresh the layer on WriteFile", func() {
+			cfs := corfs.New(writeFileFS{base}, layer)
+			Expect(readFile(cfs, "dir/file.txt")).To(Equal("base content"))
+
+			Expect(cfs.WriteFile("dir/file.txt", []byte("updated"), 0o644)).To(Succeed())
+
+			Expect(readFile(base, "dir/file.txt")).To(Equal("updated"))
+			Expect(readFile(layer, "dir/file.txt")).To(Equal("updated"))
+			Expect(readFile(cfs, "dir/file.txt")).To(Equal("updated"))
+		})
+
+		It("should record created files in the index", func() {
+			cfs := corfs.New(base, layer, corfs.WithMaxEntries(1))
+			Expect(readFile(cfs, "dir/file.txt")).To(Equal("base content"))
+
+			f, err := cfs.Create("dir/new.txt")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = f.(io.Writer).Write([]byte("created"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f.Close()).To(Succeed())
+
+			Expect(readFile(layer, "dir/new.txt")).To(Equal("created"))
+			_, err = layer.Stat("dir/file.txt")
+			Expect(err).To(MatchError(ihfs.ErrNotExist))
+		})
+
+		It("should not cache writes the base rejected", func() {
+			writeFile(layer, "file.txt", "old")
+			base := testfs.New(
+				testfs.WithCreate(func(string) (ihfs.File, error) {
+					return &testfs.File{
+						WriteFunc: func([]byte) (int, error) {
+							return 0, errors.New("disk full")
+						},
+					}, nil
+				}),
+				testfs.WithStat(func(string) (ihfs.FileInfo, error) {
+					return nil, ihfs.ErrNotExist
+				}),
+			)
+			cfs := corfs.New(base, layer)
+
+			f, err := cfs.Create("file.txt")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = f.(io.Writer).Write([]byte("new"))
+			Expect(err).To(MatchError("disk full"))
+			Expect(f.Close()).To(Succeed())
+
+			_, err = layer.Stat("file.txt")
+			Expect(err).To(MatchError(ihfs.ErrNotExist))
+		})
+
+		It("should fail WriteFile when the base is not writable", func() {
+			cfs := corfs.New(minimalFS{}, layer)
+
+			err := cfs.WriteFile("file.txt", []byte("data"), 0o644)
+
+			Expect(err).To(MatchError(ihfs.ErrNotImplemented))
+		})
+	})
+
+	Describe("removal", func() {
+		var base, layer *memfs.Fs
+
+		BeforeEach(func() {
+			base, layer = memfs.New(), memfs.New()
+			Expect(base.Mkdir("dir", 0o755)).To(Succeed())
+			writeFile(base, "dir/a.txt", "a")
+			writeFile(base, "dir/b.txt", "b")
+			writeFile(base, "c.txt", "c")
+		})
+
+		It("should remove files from the base and the layer", func() {
+			cfs := corfs.New(base, layer)
+			Expect(readFile(cfs, "c.txt")).To(Equal("c"))
+
+			Expect(cfs.Remove("c.txt")).To(Succeed())
+
+			_, err := base.Stat("c.txt")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+			_, err = layer.Stat("c.txt")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+		})
+
+		It("should remove uncached files", func() {
+			cfs := corfs.New(base, layer)
+
+			Expect(cfs.Remove("c.txt")).To(Succeed())
+
+			_, err := base.Stat("c.txt")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+		})
+
+		It("should return base errors", func() {
+			cfs := corfs.New(base, layer)
+
+			err := cfs.Remove("missing.txt")
+
+			Expect(err).To(MatchError(fs.ErrNotExist))
+		})
+
+		It("should remove files that were removed from the base elsewhere", func() {
+			cfs := corfs.New(base, layer)
+			Expect(readFile(cfs, "c.txt")).To(Equal("c"))
+			Expect(base.Remove("c.txt")).To(Succeed())
+
+			Expect(cfs.Remove("c.txt")).To(Succeed())
+
+			_, err := layer.Stat("c.txt")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+		})
+
+		It("should remove trees from the base and the layer", func() {
+			cfs := corfs.New(base, layer)
+			Expect(readFile(cfs, "dir/a.txt")).To(Equal("a"))
+
+			Expect(cfs.RemoveAll("dir")).To(Succeed())
+
+			_, err := base.Stat("dir")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+			_, err = layer.Stat("dir/a.txt")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+		})
+
+		It("should only invalidate the cache with RemoveCached", func() {
+			cfs := corfs.New(base, layer, corfs.WithRemovePolicy(corfs.RemoveCached))
+			Expect(readFile(cfs, "c.txt")).To(Equal("c"))
+			Expect(readFile(cfs, "dir/a.txt")).To(Equal("a"))
+
+			Expect(cfs.Remove("c.txt")).To(Succeed())
+			Expect(cfs.RemoveAll("dir")).To(Succeed())
+			Expect(cfs.Remove("never-cached.txt")).To(Succeed())
+
+			_, err := layer.Stat("c.txt")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+			_, err = layer.Stat("dir")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+			Expect(readFile(base, "c.txt")).To(Equal("c"))
+			Expect(readFile(base, "dir/a.txt")).To(Equal("a"))
+		})
+	})
+
+	Describe("invalidation", func() {
+		var base, layer *memfs.Fs
+
+		BeforeEach(func() {
+			base, layer = memfs.New(), memfs.New()
+			Expect(base.Mkdir("dir", 0o755)).To(Succeed())
+			writeFile(base, "dir/a.txt", "a")
+			writeFile(base, "c.txt", "c")
+		})
+
+		It("should read from the base after Invalidate", func() {
+			cfs := corfs.New(base, layer)
+			Expect(readFile(cfs, "c.txt")).To(Equal("c"))
+			writeFile(base, "c.txt", "changed")
+			Expect(readFile(cfs, "c.txt")).To(Equal("c"))
+
+			Expect(cfs.Invalidate("c.txt")).To(Succeed())
+
+			Expect(readFile(cfs, "c.txt")).To(Equal("changed"))
+		})
+
+		It("should invalidate uncached files", func() {
+			cfs := corfs.New(base, layer)
+
+			Expect(cfs.Invalidate("c.txt")).To(Succeed())
+		})
+
+		It("should purge the layer", func() {
+			cfs := corfs.New(base, layer)
+			Expect(readFile(cfs, "c.txt")).To(Equal("c"))
+			Expect(readFile(cfs, "dir/a.txt")).To(Equal("a"))
+
+			Expect(cfs.Purge()).To(Succeed())
+
+			entries, err := fs.ReadDir(layer, ".")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(BeEmpty())
+			Expect(readFile(base, "dir/a.txt")).To(Equal("a"))
+		})
+
+		It("should purge empty layers", func() {
+			cfs := corfs.New(base, minimalFS{})
+
+			Expect(cfs.Purge()).To(Succeed())
+		})
+	})
+
 	Describe("fstest", func() {
 		It("should pass fstest.TestFS", func() {
 			base := memfs.New()
@@ -0,0 +1,258 @@
+package corfs
+
+import (
+	"encoding/json"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/try"
+)
+
+// EvictionPolicy selects which cached files are removed from the layer
+// when the cache grows beyond its limits.
+type EvictionPolicy int
+
+const (
+	// LRU evicts the least recently used files first.
+	LRU EvictionPolicy = iota
+	// LFU evicts the least frequently used files first,
+	// and the least recently used of those.
+	LFU
+)
+
+// index records the files cached in the layer.
+type index struct {
+	Entries map[string]*indexEntry `json:"entries"`
+
+	size  int64
+	dirty bool
+}
+
+// indexEntry records a file cached in the layer.
+type indexEntry struct {
+	Size    int64     `json:"size"`
+	Hits    int64     `json:"hits"`
+	Used    time.Time `json:"used"`
+	Checked time.Time `json:"checked"`
+	Version string    `json:"version,omitempty"`
+}
+
+// loadIndex reads the persisted index, starting with an empty index
+// if there is none or it cannot be read.
+func (f *Fs) loadIndex() {
+	f.index = &index{Entries: map[string]*indexEntry{}}
+	if f.indexFS == nil {
+		return
+	}
+
+	data, err := fs.ReadFile(f.indexFS, f.indexName)
+	if err != nil {
+		return
+	}
+	var idx index
+	if err := json.Unmarshal(data, &idx); err != nil || idx.Entries == nil {
+		return
+	}
+	for _, e := range idx.Entries {
+		idx.size += e.Size
+	}
+	f.index = &idx
+}
+
+// version returns the recorded version of the cached copy of name.
+func (f *Fs) version(name string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if e, ok := f.index.Entries[name]; ok && e.Version != "" {
+		return e.Version, true
+	}
+	return "", false
+}
+
+// filled records that name was copied to the layer, evicting other files if
+// the cache is over its limits. An empty version records no version.
+func (f *Fs) filled(name string, size int64, version string) {
+	f.mu.Lock()
+
+	e, ok := f.index.Entries[name]
+	if !ok {
+		e = &indexEntry{}
+		f.index.Entries[name] = e
+	}
+	f.index.size += size - e.Size
+	e.Size, e.Version = size, version
+	e.Hits++
+	e.Used = time.Now()
+	e.Checked = e.Used
+
+	f.evict(name)
+	f.index.dirty = true
+	f.mu.Unlock()
+
+	_ = f.save()
+}
+
+// checked returns when the cached copy of name, described by lfi, was last copied
+// or validated. Files missing from the index fall back to their modification time.
+func (f *Fs) checked(name string, lfi ihfs.FileInfo) time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if e, ok := f.index.Entries[name]; ok && !e.Checked.IsZero() {
+		return e.Checked
+	}
+	return lfi.ModTime()
+}
+
+// validated records that the cached copy of name, described by lfi, was found
+// to be current, so that it is not validated again until the cache time passes.
+// Files missing from the index are added, as the base is known to have them.
+func (f *Fs) validated(name string, lfi ihfs.FileInfo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	e, ok := f.index.Entries[name]
+	if !ok {
+		e = &indexEntry{Size: lfi.Size(), Used: time.Now()}
+		f.index.Entries[name] = e
+		f.index.size += e.Size
+		f.evict(name)
+	}
+	e.Checked = time.Now()
+	f.index.dirty = true
+}
+
+// hit records a read of the cached copy of name. Only files that were copied
+// from the base are tracked, so files written to the layer directly are never evicted.
+func (f *Fs) hit(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if e, ok := f.index.Entries[name]; ok {
+		e.Hits++
+		e.Used = time.Now()
+		f.index.dirty = true
+	}
+}
+
+// forget removes name and everything beneath it from the index,
+// along with the cached listings and misses that include it.
+func (f *Fs) forget(name string) {
+	f.mu.Lock()
+	var changed bool
+	for n, e := range f.index.Entries {
+		if name == "." || n == name || strings.HasPrefix(n, name+"/") {
+			f.index.size -= e.Size
+			delete(f.index.Entries, n)
+			changed = true
+		}
+	}
+	f.index.dirty = f.index.dirty || changed
+	f.forgetDirs(name)
+	f.mu.Unlock()
+
+	if changed {
+		_ = f.save()
+	}
+}
+
+// evict removes files from the layer until the cache is within its limits.
+// The file named keep is never evicted. f.mu must be held.
+func (f *Fs) evict(keep string) {
+	for f.over() {
+		victim := ""
+		for n, e := range f.index.Entries {
+			if n != keep && (victim == "" || f.before(e, f.index.Entries[victim])) {
+				victim = n
+			}
+		}
+		if victim == "" {
+			return
+		}
+
+		if err := try.Remove(f.layer, victim); err != nil && !isNotExist(err) {
+			return
+		}
+		f.index.size -= f.index.Entries[victim].Size
+		delete(f.index.Entries, victim)
+	}
+}
+
+// over reports whether the cache is over its limits. f.mu must be held.
+func (f *Fs) over() bool {
+	return (f.maxBytes > 0 && f.index.size > f.maxBytes) ||
+		(f.maxEntries > 0 && len(f.index.Entries) > f.maxEntries)
+}
+
+// before reports whether a should be evicted before b.
+func (f *Fs) before(a, b *indexEntry) bool {
+	if f.eviction == LFU && a.Hits != b.Hits {
+		return a.Hits < b.Hits
+	}
+	return a.Used.Before(b.Used)
+}
+
+// save persists the index if it changed and is configured with [WithIndex].
+// The index is written without holding f.mu, so reads of the cache do not wait
+// for it, and changes made while it is written are saved by the next caller.
+func (f *Fs) save() error {
+	if f.indexFS == nil {
+		return nil
+	}
+
+	f.saving.Lock()
+	defer f.saving.Unlock()
+
+	f.mu.Lock()
+	if !f.index.dirty {
+		f.mu.Unlock()
+		return nil
+	}
+	data, err := json.Marshal(f.index)
+	f.index.dirty = err != nil
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := writeIndex(f.indexFS, f.indexName, data); err != nil {
+		f.mu.Lock()
+		f.index.dirty = true
+		f.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Flush persists the recency of cache hits that have not been saved yet.
+// Files added to or removed from the cache are saved immediately, but hits on
+// files already in the index are only saved with the next change or by Flush.
+func (f *Fs) Flush() error {
+	return f.save()
+}
+
+// writeIndex writes data to name in fsys with [ihfs.WriteFileFS] or, failing that, [ihfs.CreateFS].
+func writeIndex(fsys ihfs.FS, name string, data []byte) error {
+	if _, ok := fsys.(ihfs.WriteFileFS); ok {
+		return try.WriteFile(fsys, name, data, 0o644)
+	}
+
+	f, err := try.Create(fsys, name)
+	if err != nil {
+		return err
+	}
+	w, ok := f.(io.Writer)
+	if !ok {
+		_ = f.Close()
+		return &ihfs.PathError{Op: "write", Path: name, Err: ihfs.ErrNotImplemented}
+	}
+	if _, err := w.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,170 @@
+package corfs_test
+
+import (
+	"encoding/json"
+	"io/fs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/unstoppablemango/ihfs/corfs"
+	"github.com/unstoppablemango/ihfs/memfs"
+)
+
+var _ = Describe("Eviction", func() {
+	var base, layer *memfs.Fs
+
+	BeforeEach(func() {
+		base, layer = memfs.New(), memfs.New()
+		writeFile(base, "a.txt", "aaaa")
+		writeFile(base, "b.txt", "bbbb")
+		writeFile(base, "c.txt", "cccc")
+	})
+
+	// cached returns the names of the files in the layer.
+	cached := func() []string {
+		GinkgoHelper()
+		entries, err := fs.ReadDir(layer, ".")
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, e := range entries {
+			if !e.IsDir() {
+				names = append(names, e.Name())
+			}
+		}
+		return names
+	}
+
+	It("should evict the least recently used files over the entry limit", func() {
+		cfs := corfs.New(base, layer, corfs.WithMaxEntries(2))
+
+		readFile(cfs, "a.txt")
+		readFile(cfs, "b.txt")
+		readFile(cfs, "a.txt")
+		readFile(cfs, "c.txt")
+
+		Expect(cached()).To(ConsistOf("a.txt", "c.txt"))
+	})
+
+	It("should never evict files that are only in the layer", func() {
+		writeFile(layer, "local.txt", "local")
+		cfs := corfs.New(base, layer, corfs.WithMaxEntries(1))
+
+		Expect(readFile(cfs, "local.txt")).To(Equal("local"))
+		readFile(cfs, "a.txt")
+		readFile(cfs, "b.txt")
+
+		Expect(cached()).To(ConsistOf("local.txt", "b.txt"))
+	})
+
+	It("should evict files over the size limit", func() {
+		cfs := corfs.New(base, layer, corfs.WithMaxBytes(10))
+
+		readFile(cfs, "a.txt")
+		readFile(cfs, "b.txt")
+		Expect(cached()).To(ConsistOf("a.txt", "b.txt"))
+		readFile(cfs, "c.txt")
+
+		Expect(cached()).To(ConsistOf("b.txt", "c.txt"))
+	})
+
+	It("should keep files larger than the size limit until the next fill", func() {
+		cfs := corfs.New(base, layer, corfs.WithMaxBytes(2))
+
+		Expect(readFile(cfs, "a.txt")).To(Equal("aaaa"))
+		Expect(cached()).To(ConsistOf("a.txt"))
+		Expect(readFile(cfs, "b.txt")).To(Equal("bbbb"))
+
+		Expect(cached()).To(ConsistOf("b.txt"))
+	})
+
+	It("should evict the least frequently used files with LFU", func() {
+		cfs := corfs.New(base, layer, corfs.WithMaxEntries(2), corfs.WithEviction(corfs.LFU))
+
+		readFile(cfs, "a.txt")
+		readFile(cfs, "a.txt")
+		readFile(cfs, "b.txt")
+		readFile(cfs, "c.txt")
+
+		Expect(cached()).To(ConsistOf("a.txt", "c.txt"))
+	})
+
+	It("should update recency on Stat", func() {
+		cfs := corfs.New(base, layer, corfs.WithMaxEntries(2))
+		readFile(cfs, "a.txt")
+		readFile(cfs, "b.txt")
+
+		info, err := cfs.Stat("a.txt")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Size()).To(Equal(int64(4)))
+		readFile(cfs, "c.txt")
+
+		Expect(cached()).To(ConsistOf("a.txt", "c.txt"))
+	})
+
+	It("should stat uncached files without caching them", func() {
+		cfs := corfs.New(base, layer)
+
+		info, err := cfs.Stat("a.txt")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Name()).To(Equal("a.txt"))
+		Expect(cached()).To(BeEmpty())
+	})
+
+	It("should stop tracking invalidated files", func() {
+		cfs := corfs.New(base, layer, corfs.WithMaxEntries(2))
+		readFile(cfs, "a.txt")
+		readFile(cfs, "b.txt")
+
+		Expect(cfs.Invalidate("a.txt")).To(Succeed())
+		readFile(cfs, "c.txt")
+
+		Expect(cached()).To(ConsistOf("b.txt", "c.txt"))
+	})
+
+	Describe("persisted index", func() {
+		var indexFS *memfs.Fs
+
+		BeforeEach(func() {
+			indexFS = memfs.New()
+		})
+
+		It("should save cached files", func() {
+			cfs := corfs.New(base, layer, corfs.WithIndex(indexFS, "index.json"))
+
+			readFile(cfs, "a.txt")
+
+			var index struct {
+				Entries map[string]struct{ Size int64 }
+			}
+			Expect(json.Unmarshal([]byte(readFile(indexFS, "index.json")), &index)).To(Succeed())
+			Expect(index.Entries).To(HaveKeyWithValue("a.txt", HaveField("Size", int64(4))))
+		})
+
+		It("should evict by the use recorded before a restart", func() {
+			cfs := corfs.New(base, layer, corfs.WithIndex(indexFS, "index.json"))
+			readFile(cfs, "a.txt")
+			readFile(cfs, "b.txt")
+			readFile(cfs, "a.txt")
+			Expect(cfs.Flush()).To(Succeed())
+
+			cfs = corfs.New(base, layer,
+				corfs.WithIndex(indexFS, "index.json"),
+				corfs.WithMaxEntries(2),
+			)
+			readFile(cfs, "c.txt")
+
+			Expect(cached()).To(ConsistOf("a.txt", "c.txt"))
+		})
+
+		It("should start empty when the index cannot be read", func() {
+			writeFile(indexFS, "index.json", "not json")
+
+			cfs := corfs.New(base, layer, corfs.WithIndex(indexFS, "index.json"))
+
+			Expect(readFile(cfs, "a.txt")).To(Equal("aaaa"))
+			Expect(readFile(indexFS, "index.json")).To(ContainSubstring("a.txt"))
+		})
+	})
+})
@@ -3,6 +3,7 @@ package corfs
 import (
 	"time"
 
+	"github.com/unstoppablemango/ihfs"
 	"github.com/unstoppablemango/ihfs/union"
 )
 
@@ -16,6 +17,80 @@ func WithCacheTime(cacheTime time.Duration) Option {
 	}
 }
 
+// WithValidator sets how cached files are validated once they are older than
+// the cache time. By default, a cached file is stale when the base file has a
+// newer modification time.
+func WithValidator(validator Validator) Option {
+	return func(f *Fs) {
+		f.validator = validator
+	}
+}
+
+// WithMaxBytes limits the total size of the files cached in the layer.
+// When it is exceeded, files are evicted according to the [EvictionPolicy].
+func WithMaxBytes(n int64) Option {
+	return func(f *Fs) {
+		f.maxBytes = n
+	}
+}
+
+// WithMaxEntries limits the number of files cached in the layer.
+// When it is exceeded, files are evicted according to the [EvictionPolicy].
+func WithMaxEntries(n int) Option {
+	return func(f *Fs) {
+		f.maxEntries = n
+	}
+}
+
+// WithEviction sets which files are evicted first when the cache is over its limits.
+// The default is [LRU].
+func WithEviction(policy EvictionPolicy) Option {
+	return func(f *Fs) {
+		f.eviction = policy
+	}
+}
+
+// WithIndex persists the index of cached files to name in fsys, so that sizes,
+// use and versions survive restarts. The index is loaded by [New].
+func WithIndex(fsys ihfs.FS, name string) Option {
+	return func(f *Fs) {
+		f.indexFS = fsys
+		f.indexName = name
+	}
+}
+
+// WithDirCache caches directory listings in memory. Listings expire after the
+// cache time, and are used to answer Stat for paths that are not in the layer.
+func WithDirCache() Option {
+	return func(f *Fs) {
+		f.dirCache = true
+	}
+}
+
+// WithNegativeCache remembers paths that do not exist in the base for ttl,
+// so that repeated lookups of missing paths do not reach the base.
+func WithNegativeCache(ttl time.Duration) Option {
+	return func(f *Fs) {
+		f.negativeTTL = ttl
+	}
+}
+
+// WithMaxMisses limits how many missing paths [WithNegativeCache] remembers,
+// forgetting the oldest ones first. The default is 10000; 0 disables the negative cache.
+func WithMaxMisses(n int) Option {
+	return func(f *Fs) {
+		f.maxMisses = n
+	}
+}
+
+// WithRemovePolicy sets what Remove and RemoveAll remove, see [RemovePolicy].
+// The default is [RemoveBoth].
+func WithRemovePolicy(policy RemovePolicy) Option {
+	return func(f *Fs) {
+		f.remove = policy
+	}
+}
+
 // WithMergeStrategy sets the merge strategy for the corfs [Fs].
 func WithMergeStrategy(strategy union.MergeStrategy) Option {
 	return func(f *Fs) {
@@ -0,0 +1,100 @@
+package corfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/try"
+)
+
+// Validator decides whether a cached file is still current. Once a cached file
+// is older than the cache time, the version of the base file is compared to the
+// version of the file when it was cached, and the file is copied again if they differ.
+type Validator interface {
+	// Version returns a token describing the content of name in fsys,
+	// where info is the result of stating name in fsys.
+	Version(fsys ihfs.FS, name string, info ihfs.FileInfo) (string, error)
+}
+
+// ValidatorFunc adapts a function to a [Validator].
+type ValidatorFunc func(fsys ihfs.FS, name string, info ihfs.FileInfo) (string, error)
+
+// Version implements [Validator].
+func (fn ValidatorFunc) Version(fsys ihfs.FS, name string, info ihfs.FileInfo) (string, error) {
+	return fn(fsys, name, info)
+}
+
+// ModTimeSize validates cached files by their modification time, truncated to
+// the second, and their size. Unlike the default comparison of modification
+// times, a change of size is noticed within the same second.
+func ModTimeSize() Validator {
+	return ValidatorFunc(func(_ ihfs.FS, _ string, info ihfs.FileInfo) (string, error) {
+		return fmt.Sprintf("%d/%d", info.ModTime().Unix(), info.Size()), nil
+	})
+}
+
+// ContentHash validates cached files by the SHA-256 of their content. Each
+// validation reads the whole base file, so it suits bases that are cheap to read
+// but have no reliable modification times.
+func ContentHash() Validator {
+	return ValidatorFunc(func(fsys ihfs.FS, name string, _ ihfs.FileInfo) (string, error) {
+		f, err := fsys.Open(name)
+		if err != nil {
+			return "", err
+		}
+		defer func() { _ = f.Close() }()
+
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	})
+}
+
+// VersionToken validates cached files by the opaque version reported by a base
+// that implements [ihfs.VersionFS], such as an ETag or a blob SHA. The version of
+// the base file is recorded in the index when the file is cached, so the layer
+// does not need to implement [ihfs.VersionFS]. Files cached by an earlier [Fs]
+// have no recorded version and are copied again on their first validation,
+// unless the index is persisted with [WithIndex].
+func VersionToken() Validator {
+	return ValidatorFunc(func(fsys ihfs.FS, name string, _ ihfs.FileInfo) (string, error) {
+		return try.Version(fsys, name)
+	})
+}
+
+// stale reports whether the cached copy of name, described by lfi, is older than
+// the base file described by bfi. It also returns the version of the base file,
+// which is recorded once the file has been copied again.
+func (f *Fs) stale(name string, lfi, bfi ihfs.FileInfo) (bool, string, error) {
+	if f.validator == nil {
+		return bfi.ModTime().After(lfi.ModTime()), "", nil
+	}
+
+	version, err := f.baseVersion(name, bfi)
+	if err != nil {
+		return false, "", err
+	}
+
+	cached, ok := f.version(name)
+	if !ok {
+		if cached, err = f.validator.Version(f.layer, name, lfi); err != nil {
+			return true, version, nil
+		}
+	}
+
+	return cached != version, version, nil
+}
+
+// baseVersion returns the version of the base file name, described by bfi,
+// to record in the index when it is cached. It is empty without a [Validator].
+func (f *Fs) baseVersion(name string, bfi ihfs.FileInfo) (string, error) {
+	if f.validator == nil {
+		return "", nil
+	}
+	return f.validator.Version(f.base, name, bfi)
+}
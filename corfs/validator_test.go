@@ -0,0 +1,185 @@
+package corfs_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/corfs"
+	"github.com/unstoppablemango/ihfs/memfs"
+)
+
+// versionFS reports a fixed version for every file of a memfs.Fs.
+type versionFS struct {
+	*memfs.Fs
+	version *string
+}
+
+func (v versionFS) Version(name string) (string, error) {
+	return *v.version, nil
+}
+
+// countingVersionFS reports a fixed version for every file of a countingFS.
+type countingVersionFS struct {
+	*countingFS
+	version string
+}
+
+func (c countingVersionFS) Version(name string) (string, error) {
+	return c.version, nil
+}
+
+var _ = Describe("Validator", func() {
+	var (
+		base, layer *memfs.Fs
+		modTime     time.Time
+	)
+
+	// rewrite replaces the content of file.txt in the base, keeping its modification time.
+	rewrite := func(content string) {
+		writeFile(base, "file.txt", content)
+		Expect(base.Chtimes("file.txt", modTime, modTime)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		base, layer = memfs.New(), memfs.New()
+		modTime = time.Now().Add(-time.Hour).Truncate(time.Second)
+		rewrite("original")
+	})
+
+	It("should miss changes with the same modification time by default", func() {
+		cfs := corfs.New(base, layer, corfs.WithCacheTime(time.Nanosecond))
+		Expect(readFile(cfs, "file.txt")).To(Equal("original"))
+
+		rewrite("changed content")
+
+		Expect(readFile(cfs, "file.txt")).To(Equal("original"))
+	})
+
+	It("should notice size changes with ModTimeSize", func() {
+		cfs := corfs.New(base, layer,
+			corfs.WithCacheTime(time.Nanosecond),
+			corfs.WithValidator(corfs.ModTimeSize()),
+		)
+		Expect(readFile(cfs, "file.txt")).To(Equal("original"))
+
+		rewrite("changed content")
+
+		Expect(readFile(cfs, "file.txt")).To(Equal("changed content"))
+	})
+
+	Describe("ContentHash", func() {
+		var cfs *corfs.Fs
+
+		BeforeEach(func() {
+			cfs = corfs.New(base, layer,
+				corfs.WithCacheTime(time.Nanosecond),
+				corfs.WithValidator(corfs.ContentHash()),
+			)
+			Expect(readFile(cfs, "file.txt")).To(Equal("original"))
+		})
+
+		It("should notice content changes", func() {
+			rewrite("modified")
+
+			Expect(readFile(cfs, "file.txt")).To(Equal("modified"))
+		})
+
+		It("should keep the cached copy when only the modification time changes", func() {
+			newer := modTime.Add(time.Minute)
+			Expect(base.Chtimes("file.txt", newer, newer)).To(Succeed())
+
+			Expect(readFile(cfs, "file.txt")).To(Equal("original"))
+			info, err := layer.Stat("file.txt")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.ModTime()).To(Equal(modTime))
+		})
+	})
+
+	Describe("VersionToken", func() {
+		var (
+			version string
+			cfs     *corfs.Fs
+		)
+
+		BeforeEach(func() {
+			version = "v1"
+			cfs = corfs.New(versionFS{base, &version}, layer,
+				corfs.WithCacheTime(time.Nanosecond),
+				corfs.WithValidator(corfs.VersionToken()),
+			)
+			Expect(readFile(cfs, "file.txt")).To(Equal("original"))
+		})
+
+		It("should keep the cached copy while the version is unchanged", func() {
+			rewrite("changed")
+
+			Expect(readFile(cfs, "file.txt")).To(Equal("original"))
+		})
+
+		It("should copy the file again when the version changes", func() {
+			Expect(readFile(cfs, "file.txt")).To(Equal("original"))
+			rewrite("changed")
+			version = "v2"
+
+			Expect(readFile(cfs, "file.txt")).To(Equal("changed"))
+		})
+
+		It("should record the new version of invalidated files", func() {
+			Expect(cfs.Invalidate("file.txt")).To(Succeed())
+			rewrite("changed")
+			version = "v2"
+			Expect(readFile(cfs, "file.txt")).To(Equal("changed"))
+
+			rewrite("changed again")
+
+			Expect(readFile(cfs, "file.txt")).To(Equal("changed"))
+		})
+	})
+
+	It("should not validate before the cache time when the base has no modification times", func() {
+		modTime = time.Time{}
+		rewrite("original")
+		var validations int
+		cfs := corfs.New(base, layer,
+			corfs.WithCacheTime(time.Hour),
+			corfs.WithValidator(corfs.ValidatorFunc(func(ihfs.FS, string, ihfs.FileInfo) (string, error) {
+				validations++
+				return "v1", nil
+			})),
+		)
+		Expect(readFile(cfs, "file.txt")).To(Equal("original"))
+		Expect(validations).To(Equal(1), "the version is recorded when the file is cached")
+
+		Expect(readFile(cfs, "file.txt")).To(Equal("original"))
+		Expect(readFile(cfs, "file.txt")).To(Equal("original"))
+
+		Expect(validations).To(Equal(1))
+	})
+
+	It("should not copy freshly cached files again when the layer has no versions", func() {
+		counting := &countingFS{Fs: base, stats: map[string]int{}, opens: map[string]int{}}
+		cfs := corfs.New(countingVersionFS{counting, "v1"}, layer,
+			corfs.WithCacheTime(time.Nanosecond),
+			corfs.WithValidator(corfs.VersionToken()),
+		)
+
+		Expect(readFile(cfs, "file.txt")).To(Equal("original"))
+		Expect(readFile(cfs, "file.txt")).To(Equal("original"))
+
+		Expect(counting.opens["file.txt"]).To(Equal(1))
+	})
+
+	It("should return validation errors", func() {
+		cfs := corfs.New(base, layer,
+			corfs.WithCacheTime(time.Nanosecond),
+			corfs.WithValidator(corfs.VersionToken()),
+		)
+
+		_, err := cfs.Open("file.txt")
+
+		Expect(err).To(MatchError(ihfs.ErrNotImplemented))
+	})
+})
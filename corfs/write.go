@@ -0,0 +1,62 @@
+package corfs
+
+import (
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/try"
+)
+
+// writer is a file created through the cache. Writes go to the base only,
+// and the cached copy is refreshed from the base when the file is closed.
+type writer struct {
+	ihfs.File
+	fsys *Fs
+	name string
+}
+
+// Close implements [fs.File].
+func (w *writer) Close() error {
+	if err := w.File.Close(); err != nil {
+		_ = w.fsys.Invalidate(w.name)
+		return err
+	}
+
+	return w.fsys.refresh(w.name)
+}
+
+// Write implements [ihfs.Writer].
+func (w *writer) Write(p []byte) (int, error) {
+	return try.Write(w.File, p)
+}
+
+// Seek implements [io.Seeker].
+func (w *writer) Seek(offset int64, whence int) (int64, error) {
+	return try.Seek(w.File, offset, whence)
+}
+
+// refresh copies name from the base to the layer after it was written and records
+// it in the index. If the file cannot be copied, the cached copy is invalidated so
+// that the next read goes to the base.
+func (f *Fs) refresh(name string) error {
+	// A fill that started before the write may carry the old content
+	f.mu.Lock()
+	c, ok := f.fills[name]
+	f.mu.Unlock()
+	if ok {
+		<-c.done
+	}
+
+	f.forget(name)
+	bfi, err := try.Stat(f.base, name)
+	var version string
+	if err == nil {
+		version, err = f.baseVersion(name, bfi)
+	}
+	if err == nil {
+		err = f.fill(name, bfi, version)
+	}
+	if err != nil {
+		return f.Invalidate(name)
+	}
+
+	return nil
+}
@@ -0,0 +1,314 @@
+package ctrfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/fs"
+	"maps"
+	"path"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/unstoppablemango/ihfs"
+)
+
+// Diff creates a [v1.Layer] containing the changes that turn lower into upper, both rooted at dir.
+//
+// Entries are compared by type, mode, ownership, xattrs, symlink target and content hash, as they would
+// be written to the layer; modification times are ignored.
+// Added and changed entries are written in full, along with any parent directories needed to extract
+// them. Deleted paths are recorded with OCI whiteout entries (".wh.<name>"). When every entry of a
+// directory in lower was removed, the directory is marked opaque (".wh..wh..opq") instead.
+//
+// A lower that does not contain dir is treated as empty.
+// Like [ToLayer], the layer is streamed from lower and upper on demand, so neither may change while it is in use.
+func Diff(lower, upper ihfs.FS, dir string, options ...LayerOption) (v1.Layer, error) {
+	layer := newStreamLayer(func(w io.Writer, options ...LayerOption) error {
+		return writeDiff(lower, upper, dir, w, options...)
+	}, options)
+	if err := layer.compute(); err != nil {
+		return nil, err
+	}
+	return layer, nil
+}
+
+// writeDiff writes a gzip-compressed tar archive of the changes between lower and upper rooted at dir to w.
+func writeDiff(lower, upper ihfs.FS, dir string, w io.Writer, options ...LayerOption) error {
+	lw := newLayerWriter(w, options...)
+	d := &differ{
+		lower: lw.fs(lower),
+		upper: lw.fs(upper),
+		dir:   dir,
+		lw:    lw,
+	}
+
+	var inLower bool
+	if info, err := fs.Stat(lower, dir); err == nil {
+		inLower = info.IsDir()
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := d.diffDir(dir, inLower); err != nil {
+		return err
+	}
+	return d.lw.Close()
+}
+
+// differ walks two file systems side by side and writes their differences to a layer.
+type differ struct {
+	lower, upper ihfs.FS
+	dir          string
+	lw           *layerWriter
+
+	// pending holds unchanged ancestor directories that have not been written yet.
+	// They are only written once a change is found beneath them.
+	pending []pendingDir
+}
+
+type pendingDir struct {
+	path  string
+	entry fs.DirEntry
+}
+
+// diffDir compares the directory p in both file systems. If inLower is false,
+// p does not exist as a directory in lower and all of its contents are added.
+func (d *differ) diffDir(p string, inLower bool) error {
+	entries, err := fs.ReadDir(d.upper, p)
+	if err != nil {
+		return err
+	}
+
+	lower := map[string]fs.DirEntry{}
+	if inLower {
+		lowerEntries, err := fs.ReadDir(d.lower, p)
+		if err != nil {
+			return err
+		}
+		for _, e := range lowerEntries {
+			lower[e.Name()] = e
+		}
+	}
+
+	if err := d.whiteouts(p, entries, lower); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		child := path.Join(p, e.Name())
+		le, ok := lower[e.Name()]
+
+		if e.IsDir() {
+			if ok && le.IsDir() {
+				if err := d.diffSubdir(child, le, e); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.flush(); err != nil {
+				return err
+			}
+			if err := d.writeTree(child); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if ok {
+			if changed, err := d.changed(child, le, e); err != nil {
+				return err
+			} else if !changed {
+				continue
+			}
+		}
+		if err := d.write(child, e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// diffSubdir compares the directory child, which exists in both file systems.
+func (d *differ) diffSubdir(child string, lower, upper fs.DirEntry) error {
+	changed, err := d.changed(child, lower, upper)
+	if err != nil {
+		return err
+	}
+	if changed {
+		if err := d.write(child, upper); err != nil {
+			return err
+		}
+	} else {
+		d.pending = append(d.pending, pendingDir{child, upper})
+	}
+
+	if err := d.diffDir(child, true); err != nil {
+		return err
+	}
+
+	if n := len(d.pending); n > 0 && d.pending[n-1].path == child {
+		d.pending = d.pending[:n-1]
+	}
+	return nil
+}
+
+// whiteouts writes whiteout entries for the entries of lower that no longer exist in upper.
+func (d *differ) whiteouts(p string, upper []fs.DirEntry, lower map[string]fs.DirEntry) error {
+	if len(lower) == 0 {
+		return nil
+	}
+
+	remaining := map[string]bool{}
+	for _, e := range upper {
+		remaining[e.Name()] = true
+	}
+
+	var deleted []string
+	for _, e := range sortedEntries(lower) {
+		if !remaining[e.Name()] {
+			deleted = append(deleted, e.Name())
+		}
+	}
+	if len(deleted) == 0 {
+		return nil
+	}
+
+	if err := d.flush(); err != nil {
+		return err
+	}
+	if len(deleted) == len(lower) {
+		return d.lw.writeWhiteout(path.Join(d.name(p), opaqueWhiteout))
+	}
+	for _, name := range deleted {
+		if err := d.lw.writeWhiteout(path.Join(d.name(p), whiteoutPrefix+name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// changed reports whether the entry p differs between lower and upper.
+func (d *differ) changed(p string, lower, upper fs.DirEntry) (bool, error) {
+	li, err := lower.Info()
+	if err != nil {
+		return false, err
+	}
+	ui, err := upper.Info()
+	if err != nil {
+		return false, err
+	}
+
+	// The headers are compared as they would be written, so that modes which
+	// WithReproducible normalizes to the same value are not reported as changes.
+	lh, err := d.lw.header(d.lower, p, d.name(p), lower)
+	if err != nil {
+		return false, err
+	}
+	uh, err := d.lw.header(d.upper, p, d.name(p), upper)
+	if err != nil {
+		return false, err
+	}
+	if lh.Typeflag != uh.Typeflag || lh.Mode != uh.Mode || !sameMetadata(lh, uh) {
+		return true, nil
+	}
+
+	switch {
+	case ui.Mode().Type() == fs.ModeSymlink:
+		return lh.Linkname != uh.Linkname, nil
+	case ui.Mode().IsRegular():
+		if li.Size() != ui.Size() {
+			return true, nil
+		}
+		lh, err := contentHash(d.lower, p)
+		if err != nil {
+			return false, err
+		}
+		uh, err := contentHash(d.upper, p)
+		if err != nil {
+			return false, err
+		}
+		return !bytes.Equal(lh, uh), nil
+	default:
+		return false, nil
+	}
+}
+
+// sameMetadata reports whether a and b record the same ownership, device numbers and xattrs.
+func sameMetadata(a, b *tar.Header) bool {
+	if a.Uid != b.Uid || a.Gid != b.Gid || a.Devmajor != b.Devmajor || a.Devminor != b.Devminor {
+		return false
+	}
+	return maps.Equal(xattrRecords(a), xattrRecords(b))
+}
+
+// xattrRecords returns the PAX records of hdr that hold xattrs.
+func xattrRecords(hdr *tar.Header) map[string]string {
+	records := map[string]string{}
+	for k, v := range hdr.PAXRecords {
+		if strings.HasPrefix(k, xattrPrefix) {
+			records[k] = v
+		}
+	}
+	return records
+}
+
+// write writes the upper entry e at p, preceded by any pending parent directories.
+func (d *differ) write(p string, e fs.DirEntry) error {
+	if err := d.flush(); err != nil {
+		return err
+	}
+	return d.lw.writeEntry(d.upper, p, d.name(p), e)
+}
+
+// writeTree writes p and everything beneath it in upper.
+func (d *differ) writeTree(p string) error {
+	return fs.WalkDir(d.upper, p, func(p string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return d.lw.writeEntry(d.upper, p, d.name(p), e)
+	})
+}
+
+// flush writes all pending parent directories.
+func (d *differ) flush() error {
+	for _, dir := range d.pending {
+		if err := d.lw.writeEntry(d.upper, dir.path, d.name(dir.path), dir.entry); err != nil {
+			return err
+		}
+	}
+	d.pending = nil
+	return nil
+}
+
+func (d *differ) name(p string) string {
+	return entryName(p, d.dir)
+}
+
+// contentHash returns the SHA-256 hash of the file name in fsys.
+func contentHash(fsys ihfs.FS, name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, &fs.PathError{Op: "hash", Path: name, Err: err}
+	}
+	return h.Sum(nil), nil
+}
+
+// sortedEntries returns the entries of m sorted by name.
+func sortedEntries(m map[string]fs.DirEntry) []fs.DirEntry {
+	result := make([]fs.DirEntry, 0, len(m))
+	for _, e := range m {
+		result = append(result, e)
+	}
+	sortEntries(result)
+	return result
+}
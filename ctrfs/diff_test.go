@@ -0,0 +1,226 @@
+package ctrfs_test
+
+import (
+	"archive/tar"
+	"errors"
+	"io/fs"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/ctrfs"
+	"github.com/unstoppablemango/ihfs/testfs"
+)
+
+var _ = Describe("Diff", func() {
+	var lower fstest.MapFS
+
+	BeforeEach(func() {
+		lower = fstest.MapFS{
+			"etc":               {Mode: fs.ModeDir | 0755},
+			"etc/hosts":         {Data: []byte("hosts"), Mode: 0644},
+			"etc/passwd":        {Data: []byte("passwd"), Mode: 0644},
+			"var/cache/old.txt": {Data: []byte("old"), Mode: 0644},
+			"var/log/app.log":   {Data: []byte("log"), Mode: 0644},
+			"readme.md":         {Data: []byte("readme"), Mode: 0644},
+		}
+	})
+
+	diffNames := func(lower, upper ihfs.FS, dir string) []string {
+		GinkgoHelper()
+		layer, err := ctrfs.Diff(lower, upper, dir)
+		Expect(err).NotTo(HaveOccurred())
+
+		rc, err := layer.Uncompressed()
+		Expect(err).NotTo(HaveOccurred())
+		defer rc.Close()
+
+		names, err := tarNames(rc)
+		Expect(err).NotTo(HaveOccurred())
+		return names
+	}
+
+	It("should produce an empty layer for identical file systems", func() {
+		Expect(diffNames(lower, lower, ".")).To(BeEmpty())
+	})
+
+	It("should ignore modification time changes", func() {
+		upper := clone(lower)
+		upper["readme.md"].ModTime = upper["readme.md"].ModTime.AddDate(1, 0, 0)
+
+		Expect(diffNames(lower, upper, ".")).To(BeEmpty())
+	})
+
+	It("should include added files and their parent directories", func() {
+		upper := clone(lower)
+		upper["var/log/new.log"] = &fstest.MapFile{Data: []byte("new"), Mode: 0644}
+
+		Expect(diffNames(lower, upper, ".")).To(Equal([]string{"var/", "var/log/", "var/log/new.log"}))
+	})
+
+	It("should include added directories with their contents", func() {
+		upper := clone(lower)
+		upper["opt/app/bin"] = &fstest.MapFile{Data: []byte("bin"), Mode: 0755}
+
+		Expect(diffNames(lower, upper, ".")).To(Equal([]string{"opt/", "opt/app/", "opt/app/bin"}))
+	})
+
+	It("should include files with changed content", func() {
+		upper := clone(lower)
+		upper["etc/hosts"] = &fstest.MapFile{Data: []byte("HOSTS"), Mode: 0644}
+
+		Expect(diffNames(lower, upper, ".")).To(Equal([]string{"etc/", "etc/hosts"}))
+	})
+
+	It("should include files with changed modes", func() {
+		upper := clone(lower)
+		upper["readme.md"] = &fstest.MapFile{Data: []byte("readme"), Mode: 0600}
+
+		Expect(diffNames(lower, upper, ".")).To(Equal([]string{"readme.md"}))
+	})
+
+	It("should include files with changed ownership", func() {
+		upper := clone(lower)
+		upper["readme.md"] = &fstest.MapFile{Data: []byte("readme"), Mode: 0644, Sys: &tar.Header{Uid: 1000, Gid: 1000}}
+
+		Expect(diffNames(lower, upper, ".")).To(Equal([]string{"readme.md"}))
+	})
+
+	It("should include files with changed xattrs", func() {
+		upper := clone(lower)
+		upper["readme.md"] = &fstest.MapFile{Data: []byte("readme"), Mode: 0644, Sys: &tar.Header{
+			PAXRecords: map[string]string{"SCHILY.xattr.user.origin": "upstream"},
+		}}
+
+		Expect(diffNames(lower, upper, ".")).To(Equal([]string{"readme.md"}))
+	})
+
+	It("should ignore ownership changes that WithReproducible discards", func() {
+		upper := clone(lower)
+		upper["readme.md"] = &fstest.MapFile{Data: []byte("readme"), Mode: 0644, Sys: &tar.Header{Uid: 1000, Gid: 1000}}
+
+		layer, err := ctrfs.Diff(lower, upper, ".", ctrfs.WithReproducible(time.Unix(0, 0)))
+		Expect(err).NotTo(HaveOccurred())
+		rc, err := layer.Uncompressed()
+		Expect(err).NotTo(HaveOccurred())
+		defer rc.Close()
+
+		Expect(tarNames(rc)).To(BeEmpty())
+	})
+
+	It("should ignore mode changes that WithReproducible normalizes away", func() {
+		upper := clone(lower)
+		upper["readme.md"] = &fstest.MapFile{Data: []byte("readme"), Mode: 0600}
+
+		layer, err := ctrfs.Diff(lower, upper, ".", ctrfs.WithReproducible(time.Unix(0, 0)))
+		Expect(err).NotTo(HaveOccurred())
+		rc, err := layer.Uncompressed()
+		Expect(err).NotTo(HaveOccurred())
+		defer rc.Close()
+
+		Expect(tarNames(rc)).To(BeEmpty())
+	})
+
+	It("should write whiteouts for deleted files", func() {
+		upper := clone(lower)
+		delete(upper, "etc/passwd")
+
+		Expect(diffNames(lower, upper, ".")).To(Equal([]string{"etc/", "etc/.wh.passwd"}))
+	})
+
+	It("should write a whiteout for a deleted directory", func() {
+		upper := clone(lower)
+		delete(upper, "var/log/app.log")
+
+		Expect(diffNames(lower, upper, ".")).To(Equal([]string{"var/", "var/.wh.log"}))
+	})
+
+	It("should mark directories opaque when all lower entries were deleted", func() {
+		upper := clone(lower)
+		delete(upper, "var/cache/old.txt")
+		upper["var/cache/new.txt"] = &fstest.MapFile{Data: []byte("new"), Mode: 0644}
+
+		Expect(diffNames(lower, upper, ".")).To(Equal([]string{
+			"var/", "var/cache/", "var/cache/.wh..wh..opq", "var/cache/new.txt",
+		}))
+	})
+
+	It("should replace a file with a directory", func() {
+		upper := clone(lower)
+		delete(upper, "readme.md")
+		upper["readme.md/index.md"] = &fstest.MapFile{Data: []byte("index"), Mode: 0644}
+
+		Expect(diffNames(lower, upper, ".")).To(Equal([]string{"readme.md/", "readme.md/index.md"}))
+	})
+
+	It("should diff a subdirectory", func() {
+		upper := clone(lower)
+		upper["etc/hosts"] = &fstest.MapFile{Data: []byte("HOSTS"), Mode: 0644}
+		delete(upper, "etc/passwd")
+
+		Expect(diffNames(lower, upper, "etc")).To(Equal([]string{".wh.passwd", "hosts"}))
+	})
+
+	It("should treat a missing lower directory as empty", func() {
+		Expect(diffNames(fstest.MapFS{}, lower, "etc")).To(Equal([]string{"hosts", "passwd"}))
+	})
+
+	It("should produce a layer that applies on top of lower", func() {
+		base, err := ctrfs.ToLayer(lower, ".")
+		Expect(err).NotTo(HaveOccurred())
+		upper := clone(lower)
+		upper["etc/hosts"] = &fstest.MapFile{Data: []byte("HOSTS"), Mode: 0644}
+		delete(upper, "etc/passwd")
+		delete(upper, "var/cache/old.txt")
+		upper["var/cache"] = &fstest.MapFile{Mode: fs.ModeDir | 0755}
+
+		diff, err := ctrfs.Diff(lower, upper, ".")
+		Expect(err).NotTo(HaveOccurred())
+		img, err := mutate.AppendLayers(empty.Image, base, diff)
+		Expect(err).NotTo(HaveOccurred())
+		fsys, err := ctrfs.FromImageLayers(img)
+		Expect(err).NotTo(HaveOccurred())
+		defer fsys.Close()
+
+		data, err := fs.ReadFile(fsys, "etc/hosts")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("HOSTS"))
+		_, err = fsys.Open("etc/passwd")
+		Expect(err).To(MatchError(fs.ErrNotExist))
+		entries, err := fs.ReadDir(fsys, "var/cache")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("should propagate upper ReadDir errors", func() {
+		_, err := ctrfs.Diff(lower, testfs.BoringFs{}, ".")
+
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should propagate lower Stat errors", func() {
+		statErr := errors.New("stat error")
+		fsys := testfs.New(testfs.WithStat(func(string) (ihfs.FileInfo, error) {
+			return nil, statErr
+		}))
+
+		_, err := ctrfs.Diff(fsys, lower, ".")
+
+		Expect(err).To(MatchError(statErr))
+	})
+})
+
+// clone returns a shallow copy of m with copied file entries.
+func clone(m fstest.MapFS) fstest.MapFS {
+	result := make(fstest.MapFS, len(m))
+	for name, f := range m {
+		c := *f
+		result[name] = &c
+	}
+	return result
+}
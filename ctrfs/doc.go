@@ -1,4 +1,4 @@
-// Package ctrfs provides read-only fs.FS implementations for OCI container images and layers,
+// Package ctrfs provides fs.FS implementations for OCI container images and layers,
 // and write helpers for producing new OCI layers from an fs.FS.
 //
 // # Reading
@@ -19,6 +19,15 @@
 //	fsys, _ := ctrfs.FromLayer(layers[0])
 //	defer fsys.Close()
 //
+// [FromImageLayers] stacks the layers of a [github.com/google/go-containerregistry/pkg/v1.Image]
+// without flattening them, so the layer that introduced, modified or deleted each path can be
+// looked up for auditing.
+//
+//	fsys, _ := ctrfs.FromImageLayers(img)
+//	defer fsys.Close()
+//	digest, _ := fsys.Provenance("etc/os-release")
+//	history, _ := fsys.History("etc/os-release")
+//
 // # Writing
 //
 // [ToLayer] walks an [io/fs.FS] and produces a new [github.com/google/go-containerregistry/pkg/v1.Layer]
@@ -28,4 +37,32 @@
 //
 //	layer, _ := ctrfs.ToLayer(myFS, ".")
 //	newImg, _ := ctrfs.ToImage(baseImg, myFS, ".")
+//
+// [Diff] compares two [io/fs.FS] trees and produces a layer containing only the added and changed
+// entries, with OCI whiteout entries for anything that was deleted.
+//
+//	layer, _ := ctrfs.Diff(before, after, ".")
+//
+// Pass [WithReproducible] to any of the writers to produce byte-identical layers from identical trees.
+//
+//	epoch, _ := ctrfs.SourceDateEpoch()
+//	layer, _ := ctrfs.ToLayer(myFS, ".", ctrfs.WithReproducible(epoch))
+//
+// # Image layouts
+//
+// [FromLayout] reads an OCI image layout directory (index.json and blobs/) from any [io/fs.FS],
+// and [WriteLayout] writes an image into one, so images can be built and inspected without a registry.
+//
+//	_ = ctrfs.WriteLayout(memfs.New(), "layout", img)
+//	index, _ := ctrfs.FromLayout(fsys, "layout")
+//
+// # Mounting
+//
+// [Mount] returns a writable [MountFS] backed by an image. Writes and deletions are kept in memory
+// and [MountFS.Commit] appends them to the image as a new layer.
+//
+//	fsys := ctrfs.Mount(img)
+//	defer fsys.Close()
+//	_ = fsys.WriteFile("etc/motd", []byte("hello"), 0644)
+//	newImg, _ := fsys.Commit()
 package ctrfs
@@ -11,7 +11,9 @@ require (
 	github.com/google/go-containerregistry v0.21.2
 	github.com/onsi/ginkgo/v2 v2.28.1
 	github.com/onsi/gomega v1.39.1
+	github.com/unmango/go v0.15.1
 	github.com/unstoppablemango/ihfs v0.0.1
+	golang.org/x/sys v0.41.0
 )
 
 require (
@@ -31,14 +33,15 @@ require (
 	github.com/sirupsen/logrus v1.9.4 // indirect
 	github.com/spf13/cobra v1.10.2 // indirect
 	github.com/spf13/pflag v1.0.10 // indirect
-	github.com/unmango/go v0.15.1 // indirect
 	github.com/vbatts/tar-split v0.12.2 // indirect
 	go.yaml.in/yaml/v3 v3.0.4 // indirect
 	golang.org/x/mod v0.33.0 // indirect
 	golang.org/x/net v0.51.0 // indirect
 	golang.org/x/sync v0.19.0 // indirect
-	golang.org/x/sys v0.41.0 // indirect
 	golang.org/x/text v0.34.0 // indirect
 	golang.org/x/tools v0.42.0 // indirect
 	golang.org/x/tools/go/vcs v0.1.0-deprecated // indirect
 )
+
+// The root module is developed alongside ctrfs, which depends on unreleased changes to it.
+replace github.com/unstoppablemango/ihfs => ../
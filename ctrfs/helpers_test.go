@@ -66,6 +66,22 @@ func tarNames(r io.Reader) ([]string, error) {
 	return names, nil
 }
 
+// tarHeaders reads all entry headers from a tar stream, returning an error if the stream is malformed.
+func tarHeaders(r io.Reader) ([]*tar.Header, error) {
+	tr := tar.NewReader(r)
+	var headers []*tar.Header
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return headers, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, hdr)
+	}
+}
+
 func rootDirStat(name string) (ihfs.FileInfo, error) {
 	fi := testfs.NewFileInfo(name)
 	fi.IsDirFunc = func() bool { return name == "." }
@@ -89,3 +105,14 @@ func (e *errLayer) Compressed() (io.ReadCloser, error)   { return nil, e.err }
 func (e *errLayer) Uncompressed() (io.ReadCloser, error) { return nil, e.err }
 func (e *errLayer) Size() (int64, error)                 { return 0, e.err }
 func (e *errLayer) MediaType() (types.MediaType, error)  { return "", e.err }
+
+// countingLayer counts the streams opened from the wrapped layer.
+type countingLayer struct {
+	v1.Layer
+	uncompressed int
+}
+
+func (c *countingLayer) Uncompressed() (io.ReadCloser, error) {
+	c.uncompressed++
+	return c.Layer.Uncompressed()
+}
@@ -2,15 +2,16 @@ package ctrfs
 
 import (
 	"archive/tar"
-	"bytes"
 	"compress/gzip"
 	"io"
 	"io/fs"
+	"slices"
 	"strings"
+	"time"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
-	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/unmango/go/fopt"
 	"github.com/unstoppablemango/ihfs"
 	"github.com/unstoppablemango/ihfs/tarfs"
 )
@@ -33,77 +34,176 @@ func FromLayer(layer v1.Layer) (*LayerFS, error) {
 
 // ToLayer creates a [v1.Layer] from the files in fsys rooted at dir.
 // The resulting layer contains all files as a gzip-compressed tar archive.
-func ToLayer(fsys ihfs.FS, dir string) (v1.Layer, error) {
-	var compressed bytes.Buffer
-	if err := writeLayer(fsys, dir, &compressed); err != nil {
+//
+// The archive is not kept in memory. fsys is walked once to compute the digest and size,
+// and again each time the layer contents are read, so it must not change while the layer is in use.
+func ToLayer(fsys ihfs.FS, dir string, options ...LayerOption) (v1.Layer, error) {
+	layer := newStreamLayer(func(w io.Writer, options ...LayerOption) error {
+		return writeLayer(fsys, dir, w, options...)
+	}, options)
+	if err := layer.compute(); err != nil {
 		return nil, err
 	}
-	return tarball.LayerFromOpener(func() (io.ReadCloser, error) {
-		return io.NopCloser(bytes.NewReader(compressed.Bytes())), nil
-	})
+	return layer, nil
 }
 
 // writeLayer writes a gzip-compressed tar archive of fsys rooted at dir to w.
-func writeLayer(fsys ihfs.FS, dir string, w io.Writer) error {
-	gw := gzip.NewWriter(w)
-	tw := tar.NewWriter(gw)
-
+func writeLayer(fsys ihfs.FS, dir string, w io.Writer, options ...LayerOption) error {
+	lw := newLayerWriter(w, options...)
+	fsys = lw.fs(fsys)
 	err := fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
+		if p == dir && lw.omitRoot {
+			return nil
+		}
+		return lw.writeEntry(fsys, p, entryName(p, dir), d)
+	})
+	if err != nil {
+		return err
+	}
+	return lw.Close()
+}
 
-		name := entryName(p, dir)
+// layerWriter writes file system entries to a gzip-compressed tar archive.
+type layerWriter struct {
+	gw *gzip.Writer
+	tw *tar.Writer
 
-		info, err := d.Info()
-		if err != nil {
-			return err
-		}
+	reproducible bool
+	epoch        time.Time
+	ownership    OwnershipFunc
+	omitRoot     bool
+	uncompressed io.Writer
+}
 
-		var link string
-		if d.Type()&fs.ModeSymlink != 0 {
-			if link, err = fs.ReadLink(fsys, p); err != nil {
-				return err
-			}
-		}
+func newLayerWriter(w io.Writer, options ...LayerOption) *layerWriter {
+	lw := &layerWriter{}
+	fopt.ApplyAll(lw, options)
 
-		hdr, err := tar.FileInfoHeader(info, link)
-		if err != nil {
-			return err
-		}
-		hdr.Name = name
-		if d.IsDir() && name != "." {
-			hdr.Name += "/"
-		}
-		if err := tw.WriteHeader(hdr); err != nil {
-			return err
-		}
+	lw.gw = gzip.NewWriter(w)
+	if lw.reproducible {
+		// Pin the fields the gzip header could otherwise pick up from the environment.
+		lw.gw.Header = gzip.Header{OS: 255}
+	}
+	if lw.uncompressed != nil {
+		lw.tw = tar.NewWriter(io.MultiWriter(lw.gw, lw.uncompressed))
+	} else {
+		lw.tw = tar.NewWriter(lw.gw)
+	}
+	return lw
+}
 
-		if d.Type().IsRegular() {
-			f, err := fsys.Open(p)
-			if err != nil {
-				return err
-			}
-			defer func() { _ = f.Close() }()
-			if _, err := io.Copy(tw, f); err != nil {
-				return err
-			}
-		}
+// fs returns fsys wrapped so that directories are read in lexical order when output is reproducible.
+func (lw *layerWriter) fs(fsys ihfs.FS) ihfs.FS {
+	if !lw.reproducible {
+		return fsys
+	}
+	return sortedFS{fsys}
+}
+
+// writeEntry writes the entry d found at p in fsys to the archive as name.
+// The contents of regular files are copied into the archive.
+func (lw *layerWriter) writeEntry(fsys ihfs.FS, p, name string, d fs.DirEntry) error {
+	hdr, err := lw.header(fsys, p, name, d)
+	if err != nil {
+		return err
+	}
+	if err := lw.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
 
+	// Hard links from tar-backed file systems are regular files without content of their own.
+	if hdr.Typeflag != tar.TypeReg {
 		return nil
-	})
+	}
+
+	f, err := fsys.Open(p)
 	if err != nil {
 		return err
 	}
-	if err := tw.Close(); err != nil {
+	defer func() { _ = f.Close() }()
+
+	_, err = io.Copy(lw.tw, f)
+	return err
+}
+
+// header returns the tar header that describes the entry d found at p in fsys as name.
+func (lw *layerWriter) header(fsys ihfs.FS, p, name string, d fs.DirEntry) (*tar.Header, error) {
+	info, err := d.Info()
+	if err != nil {
+		return nil, err
+	}
+
+	var link string
+	if d.Type()&fs.ModeSymlink != 0 {
+		if link, err = fs.ReadLink(fsys, p); err != nil {
+			return nil, err
+		}
+	}
+
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyMetadata(hdr, fsys, p, info); err != nil {
+		return nil, err
+	}
+	hdr.Name = name
+	if d.IsDir() && name != "." {
+		hdr.Name += "/"
+	}
+	if lw.reproducible {
+		lw.normalize(hdr)
+	}
+	if lw.ownership != nil {
+		applyOwnership(hdr, name, lw.ownership)
+	}
+	return hdr, nil
+}
+
+// writeWhiteout writes an empty OCI whiteout entry with the given name.
+func (lw *layerWriter) writeWhiteout(name string) error {
+	return lw.tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Typeflag: tar.TypeReg,
+		Mode:     0644,
+	})
+}
+
+// normalize strips the parts of hdr that vary between otherwise identical trees.
+func (lw *layerWriter) normalize(hdr *tar.Header) {
+	hdr.Uid, hdr.Gid = 0, 0
+	hdr.Uname, hdr.Gname = "", ""
+	hdr.AccessTime, hdr.ChangeTime = time.Time{}, time.Time{}
+
+	hdr.ModTime = hdr.ModTime.Truncate(time.Second)
+	if hdr.ModTime.After(lw.epoch) {
+		hdr.ModTime = lw.epoch
+	}
+
+	switch {
+	case hdr.Typeflag == tar.TypeSymlink:
+		hdr.Mode = 0777
+	case hdr.Typeflag == tar.TypeDir, hdr.Mode&0111 != 0:
+		hdr.Mode = 0755
+	default:
+		hdr.Mode = 0644
+	}
+}
+
+// Close flushes the tar and gzip streams. It does not close the underlying writer.
+func (lw *layerWriter) Close() error {
+	if err := lw.tw.Close(); err != nil {
 		return err
 	}
-	return gw.Close()
+	return lw.gw.Close()
 }
 
 // ToImage appends a new layer built from fsys onto base and returns the resulting image.
-func ToImage(base v1.Image, fsys ihfs.FS, dir string) (v1.Image, error) {
-	layer, err := ToLayer(fsys, dir)
+func ToImage(base v1.Image, fsys ihfs.FS, dir string, options ...LayerOption) (v1.Image, error) {
+	layer, err := ToLayer(fsys, dir, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -120,3 +220,41 @@ func entryName(p, dir string) string {
 	}
 	return strings.TrimPrefix(p, dir+"/")
 }
+
+// sortedFS wraps an [ihfs.FS] so that [fs.ReadDir] always returns entries sorted by name,
+// even when the underlying ReadDir implementation does not.
+type sortedFS struct {
+	ihfs.FS
+}
+
+// ReadDir implements [fs.ReadDirFS].
+func (s sortedFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(s.FS, name)
+	if err != nil {
+		return nil, err
+	}
+	sortEntries(entries)
+	return entries, nil
+}
+
+// ReadLink implements [fs.ReadLinkFS].
+func (s sortedFS) ReadLink(name string) (string, error) {
+	return fs.ReadLink(s.FS, name)
+}
+
+// Lstat implements [fs.ReadLinkFS].
+func (s sortedFS) Lstat(name string) (fs.FileInfo, error) {
+	return fs.Lstat(s.FS, name)
+}
+
+// Stat implements [fs.StatFS].
+func (s sortedFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(s.FS, name)
+}
+
+// sortEntries sorts entries by name.
+func sortEntries(entries []fs.DirEntry) {
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+}
@@ -2,7 +2,9 @@ package ctrfs_test
 
 import (
 	"archive/tar"
+	"crypto/sha256"
 	"errors"
+	"fmt"
 	"io"
 	"io/fs"
 	"testing/fstest"
@@ -172,6 +174,86 @@ var _ = Describe("ToLayer", func() {
 		Expect(err).To(MatchError(readLinkErr))
 	})
 
+	It("should compute the digest, diffID and size from the streamed contents", func() {
+		fsys := fstest.MapFS{"dir/hello.txt": {Data: []byte("hello")}}
+
+		layer, err := ctrfs.ToLayer(fsys, ".")
+		Expect(err).NotTo(HaveOccurred())
+
+		rc, err := layer.Compressed()
+		Expect(err).NotTo(HaveOccurred())
+		compressed, err := io.ReadAll(rc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rc.Close()).To(Succeed())
+		rc, err = layer.Uncompressed()
+		Expect(err).NotTo(HaveOccurred())
+		uncompressed, err := io.ReadAll(rc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rc.Close()).To(Succeed())
+
+		digest, err := layer.Digest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(digest.Hex).To(Equal(fmt.Sprintf("%x", sha256.Sum256(compressed))))
+		diffID, err := layer.DiffID()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diffID.Hex).To(Equal(fmt.Sprintf("%x", sha256.Sum256(uncompressed))))
+		size, err := layer.Size()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(size).To(Equal(int64(len(compressed))))
+	})
+
+	It("should re-walk fsys each time the contents are read", func() {
+		var opens int
+		m := fstest.MapFS{"hello.txt": {Data: []byte("hello")}}
+		fsys := testfs.New(
+			testfs.WithStat(m.Stat),
+			testfs.WithReadDir(m.ReadDir),
+			testfs.WithOpen(func(name string) (ihfs.File, error) {
+				opens++
+				return m.Open(name)
+			}),
+		)
+
+		layer, err := ctrfs.ToLayer(fsys, ".")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opens).To(Equal(1))
+
+		for range 2 {
+			rc, err := layer.Compressed()
+			Expect(err).NotTo(HaveOccurred())
+			_, err = io.Copy(io.Discard, rc)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rc.Close()).To(Succeed())
+		}
+		Expect(opens).To(Equal(3))
+	})
+
+	It("should surface errors encountered while streaming", func() {
+		var failing bool
+		streamErr := errors.New("stream error")
+		m := fstest.MapFS{"hello.txt": {Data: []byte("hello")}}
+		fsys := testfs.New(
+			testfs.WithStat(m.Stat),
+			testfs.WithReadDir(m.ReadDir),
+			testfs.WithOpen(func(name string) (ihfs.File, error) {
+				if failing {
+					return nil, streamErr
+				}
+				return m.Open(name)
+			}),
+		)
+
+		layer, err := ctrfs.ToLayer(fsys, ".")
+		Expect(err).NotTo(HaveOccurred())
+		failing = true
+
+		rc, err := layer.Uncompressed()
+		Expect(err).NotTo(HaveOccurred())
+		defer rc.Close()
+		_, err = io.ReadAll(rc)
+		Expect(err).To(MatchError(streamErr))
+	})
+
 	It("should propagate walk errors", func() {
 		_, err := ctrfs.ToLayer(testfs.BoringFs{}, "nonexistent")
 
@@ -0,0 +1,416 @@
+package ctrfs
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/union"
+)
+
+const (
+	// whiteoutPrefix marks a tar entry that deletes the named path from lower layers.
+	whiteoutPrefix = ".wh."
+	// opaqueWhiteout marks a directory whose lower layer contents are hidden.
+	opaqueWhiteout = whiteoutPrefix + whiteoutPrefix + ".opq"
+)
+
+// ChangeType describes how an image layer affected a path.
+type ChangeType int
+
+const (
+	// Added indicates the path was introduced by the layer.
+	Added ChangeType = iota
+	// Modified indicates the layer replaced a path provided by a lower layer.
+	Modified
+	// Deleted indicates the path was removed by the layer, either by a
+	// whiteout entry or by replacing one of its parent directories.
+	Deleted
+)
+
+// String implements [fmt.Stringer].
+func (c ChangeType) String() string {
+	switch c {
+	case Added:
+		return "added"
+	case Modified:
+		return "modified"
+	case Deleted:
+		return "deleted"
+	default:
+		return "unknown"
+	}
+}
+
+// Change records the effect a single layer had on a path.
+type Change struct {
+	// Layer is the digest of the layer that made the change.
+	Layer v1.Hash
+	// Type is the kind of change the layer made.
+	Type ChangeType
+}
+
+// LayeredFS wraps a [v1.Image] as a read-only file system that keeps each image layer separate.
+// Layers are stacked bottom to top with OCI whiteout entries applied, and reads are served
+// from the layer that last provided the requested path. Directories are merged across layers
+// using the [union] package.
+//
+// Unlike [ImageFS], LayeredFS remembers which layer introduced, modified or deleted each path.
+// See [LayeredFS.Provenance] and [LayeredFS.History].
+//
+// The index is built from a single pass over the tar headers of each layer. The contents of a
+// layer are only read once a path it provides is opened.
+//
+// Call [LayeredFS.Close] when the FS is no longer needed to release the underlying streams.
+type LayeredFS struct {
+	layers   []v1.Layer
+	digests  []v1.Hash
+	index    map[string]*layerEntry
+	children map[string]map[string]struct{}
+	history  map[string][]Change
+
+	mu     sync.Mutex
+	opened []*LayerFS
+}
+
+// layerEntry locates a path within the layer stack.
+type layerEntry struct {
+	// layer is the layer that last added or modified the path.
+	layer int
+	// floor is the lowest layer whose directory contents are visible.
+	floor int
+	isDir bool
+}
+
+// FromImageLayers creates a read-only [io/fs.FS] from a [v1.Image] that tracks the provenance
+// of each path. The tar headers of every layer are read up front to build the index,
+// while file contents are read lazily as they are accessed.
+func FromImageLayers(img v1.Image) (*LayeredFS, error) {
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &LayeredFS{
+		layers:   layers,
+		index:    map[string]*layerEntry{},
+		children: map[string]map[string]struct{}{},
+		history:  map[string][]Change{},
+		opened:   make([]*LayerFS, len(layers)),
+	}
+	for i, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return nil, err
+		}
+
+		f.digests = append(f.digests, digest)
+		if err := f.apply(i, layer); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// Close closes the underlying layer streams.
+func (f *LayeredFS) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var errs []error
+	for i, l := range f.opened {
+		if l != nil {
+			errs = append(errs, l.Close())
+			f.opened[i] = nil
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// layer returns the file system of layer i, opening its stream on first use.
+func (f *LayeredFS) layer(i int) (*LayerFS, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.opened[i] == nil {
+		lfs, err := FromLayer(f.layers[i])
+		if err != nil {
+			return nil, err
+		}
+		f.opened[i] = lfs
+	}
+	return f.opened[i], nil
+}
+
+// Open implements [fs.FS].
+func (f *LayeredFS) Open(name string) (ihfs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, f.error("open", name, fs.ErrInvalid)
+	}
+
+	e, ok := f.index[name]
+	if !ok {
+		return nil, f.error("open", name, fs.ErrNotExist)
+	}
+	if !e.isDir {
+		lfs, err := f.layer(e.layer)
+		if err != nil {
+			return nil, err
+		}
+		return lfs.Open(name)
+	}
+
+	// Layers are stacked with the one that last provided the directory on top,
+	// so the merged directory reports its metadata.
+	var (
+		file  ihfs.File
+		merge = union.WithMergeStrategy(f.visible(name))
+	)
+	for _, i := range f.stack(e) {
+		lfs, err := f.layer(i)
+		if err != nil {
+			if file != nil {
+				_ = file.Close()
+			}
+			return nil, err
+		}
+		lf, err := lfs.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			if file != nil {
+				_ = file.Close()
+			}
+			return nil, err
+		}
+		file = union.NewFile(file, lf, merge)
+	}
+	if file == nil {
+		return nil, f.error("open", name, fs.ErrNotExist)
+	}
+
+	return file, nil
+}
+
+// Provenance returns the digest of the layer that last added or modified name.
+// If name does not exist in the final filesystem view, the returned error
+// will be an [fs.PathError] wrapping [fs.ErrNotExist].
+func (f *LayeredFS) Provenance(name string) (v1.Hash, error) {
+	if !fs.ValidPath(name) {
+		return v1.Hash{}, f.error("provenance", name, fs.ErrInvalid)
+	}
+	if e, ok := f.index[name]; ok {
+		return f.digests[e.layer], nil
+	}
+	return v1.Hash{}, f.error("provenance", name, fs.ErrNotExist)
+}
+
+// History returns the changes made to name by each layer, ordered from the bottom layer to the top.
+// Paths that were deleted are still reported so removals can be audited.
+// If no layer ever touched name, the returned error will be an [fs.PathError] wrapping [fs.ErrNotExist].
+func (f *LayeredFS) History(name string) ([]Change, error) {
+	if !fs.ValidPath(name) {
+		return nil, f.error("history", name, fs.ErrInvalid)
+	}
+	if h, ok := f.history[name]; ok {
+		return append([]Change(nil), h...), nil
+	}
+	return nil, f.error("history", name, fs.ErrNotExist)
+}
+
+// apply indexes the entries of layer i. Whiteouts only affect lower layers,
+// so they are applied before any of the layer's own entries are added.
+func (f *LayeredFS) apply(i int, layer v1.Layer) error {
+	type added struct {
+		name  string
+		isDir bool
+	}
+
+	rc, err := layer.Uncompressed()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+
+	var (
+		entries   []added
+		whiteouts []string
+		opaques   []string
+		tr        = tar.NewReader(rc)
+	)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		p := path.Clean(hdr.Name)
+		if p == "." || !fs.ValidPath(p) {
+			continue
+		}
+
+		dir, base := path.Dir(p), path.Base(p)
+		switch {
+		case base == opaqueWhiteout:
+			opaques = append(opaques, dir)
+		case strings.HasPrefix(base, whiteoutPrefix):
+			whiteouts = append(whiteouts, path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix)))
+		default:
+			entries = append(entries, added{p, hdr.Typeflag == tar.TypeDir})
+		}
+	}
+
+	if i == 0 {
+		f.index["."] = &layerEntry{isDir: true}
+	}
+	for _, name := range whiteouts {
+		f.remove(i, name, true)
+	}
+	for _, dir := range opaques {
+		f.remove(i, dir, false)
+		if e, ok := f.index[dir]; ok && e.isDir {
+			e.floor = i
+		}
+	}
+	for _, e := range entries {
+		f.parents(i, e.name)
+		f.add(i, e.name, e.isDir)
+	}
+
+	return nil
+}
+
+// parents adds any missing parent directories of name implied by layer i.
+func (f *LayeredFS) parents(i int, name string) {
+	dir := path.Dir(name)
+	if dir == "." {
+		return
+	}
+	if e, ok := f.index[dir]; ok && e.isDir {
+		return
+	}
+
+	f.parents(i, dir)
+	f.add(i, dir, true)
+}
+
+// add records that layer i provides name.
+func (f *LayeredFS) add(i int, name string, isDir bool) {
+	prev, ok := f.index[name]
+	if !ok {
+		f.index[name] = &layerEntry{layer: i, floor: i, isDir: isDir}
+		f.child(name, true)
+		f.record(i, name, Added)
+		return
+	}
+
+	if !isDir || !prev.isDir {
+		// The entry replaces a lower path of a different kind, so nothing
+		// below it in lower layers remains visible.
+		f.remove(i, name, false)
+		prev.floor = i
+	}
+	prev.layer, prev.isDir = i, isDir
+	f.record(i, name, Modified)
+}
+
+// remove deletes the descendants of name from the index, along with name itself if self is true.
+// Only the subtree of name is visited.
+func (f *LayeredFS) remove(i int, name string, self bool) {
+	for child := range f.children[name] {
+		f.remove(i, child, true)
+	}
+	if !self || name == "." {
+		return
+	}
+	if _, ok := f.index[name]; ok {
+		delete(f.index, name)
+		delete(f.children, name)
+		f.child(name, false)
+		f.record(i, name, Deleted)
+	}
+}
+
+// child adds name to, or removes it from, the children of its parent directory.
+func (f *LayeredFS) child(name string, add bool) {
+	dir := path.Dir(name)
+	if !add {
+		delete(f.children[dir], name)
+		return
+	}
+	if f.children[dir] == nil {
+		f.children[dir] = map[string]struct{}{}
+	}
+	f.children[dir][name] = struct{}{}
+}
+
+func (f *LayeredFS) record(i int, name string, typ ChangeType) {
+	f.history[name] = append(f.history[name], Change{
+		Layer: f.digests[i],
+		Type:  typ,
+	})
+}
+
+// stack returns the indexes of the layers that may contribute to the directory e,
+// ordered from bottom to top with e.layer last.
+func (f *LayeredFS) stack(e *layerEntry) []int {
+	result := make([]int, 0, len(f.layers)-e.floor)
+	for i := e.floor; i < len(f.layers); i++ {
+		if i != e.layer {
+			result = append(result, i)
+		}
+	}
+	return append(result, e.layer)
+}
+
+// visible returns a [union.MergeStrategy] for the directory dir that drops
+// whiteout entries and paths hidden by upper layers. Entries are taken from
+// the layer that last provided each path so they match [LayeredFS.Open].
+func (f *LayeredFS) visible(dir string) union.MergeStrategy {
+	return func(layer, base []ihfs.DirEntry) ([]ihfs.DirEntry, error) {
+		merged, err := union.DefaultMergeStrategy(layer, base)
+		if err != nil {
+			return nil, err
+		}
+
+		result := make([]ihfs.DirEntry, 0, len(merged))
+		for _, entry := range merged {
+			name := path.Join(dir, entry.Name())
+			e, ok := f.index[name]
+			if !ok {
+				continue
+			}
+
+			lfs, err := f.layer(e.layer)
+			if err != nil {
+				return nil, err
+			}
+			file, err := lfs.Open(name)
+			if err != nil {
+				return nil, err
+			}
+			info, err := file.Stat()
+			_ = file.Close()
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, fs.FileInfoToDirEntry(info))
+		}
+		return result, nil
+	}
+}
+
+func (f *LayeredFS) error(op, name string, err error) error {
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
@@ -0,0 +1,273 @@
+package ctrfs_test
+
+import (
+	"archive/tar"
+	"errors"
+	"io/fs"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/unstoppablemango/ihfs/ctrfs"
+)
+
+var _ = Describe("LayeredFS", func() {
+	var (
+		lower, upper v1.Layer
+		lowerDigest  v1.Hash
+		upperDigest  v1.Hash
+		fsys         *ctrfs.LayeredFS
+	)
+
+	BeforeEach(func() {
+		var err error
+		lower, err = makeLayer([]tarEntry{
+			{hdr: &tar.Header{Name: "etc/", Typeflag: tar.TypeDir, Mode: 0755}},
+			{hdr: &tar.Header{Name: "etc/hosts", Typeflag: tar.TypeReg, Size: 5, Mode: 0644}, data: "hosts"},
+			{hdr: &tar.Header{Name: "etc/passwd", Typeflag: tar.TypeReg, Size: 6, Mode: 0644}, data: "passwd"},
+			{hdr: &tar.Header{Name: "var/", Typeflag: tar.TypeDir, Mode: 0755}},
+			{hdr: &tar.Header{Name: "var/cache/", Typeflag: tar.TypeDir, Mode: 0755}},
+			{hdr: &tar.Header{Name: "var/cache/old.txt", Typeflag: tar.TypeReg, Size: 3, Mode: 0644}, data: "old"},
+			{hdr: &tar.Header{Name: "readme.md", Typeflag: tar.TypeReg, Size: 6, Mode: 0644}, data: "readme"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		upper, err = makeLayer([]tarEntry{
+			{hdr: &tar.Header{Name: "etc/", Typeflag: tar.TypeDir, Mode: 0755}},
+			{hdr: &tar.Header{Name: "etc/hosts", Typeflag: tar.TypeReg, Size: 7, Mode: 0644}, data: "updated"},
+			{hdr: &tar.Header{Name: "etc/.wh.passwd", Typeflag: tar.TypeReg, Mode: 0644}},
+			{hdr: &tar.Header{Name: "var/cache/", Typeflag: tar.TypeDir, Mode: 0755}},
+			{hdr: &tar.Header{Name: "var/cache/.wh..wh..opq", Typeflag: tar.TypeReg, Mode: 0644}},
+			{hdr: &tar.Header{Name: "var/cache/new.txt", Typeflag: tar.TypeReg, Size: 3, Mode: 0644}, data: "new"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		lowerDigest, err = lower.Digest()
+		Expect(err).NotTo(HaveOccurred())
+		upperDigest, err = upper.Digest()
+		Expect(err).NotTo(HaveOccurred())
+
+		img, err := mutate.AppendLayers(empty.Image, lower, upper)
+		Expect(err).NotTo(HaveOccurred())
+
+		fsys, err = ctrfs.FromImageLayers(img)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(fsys.Close)
+	})
+
+	It("should read a file from the layer that last modified it", func() {
+		data, err := fs.ReadFile(fsys, "etc/hosts")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("updated"))
+	})
+
+	It("should read a file from a lower layer", func() {
+		data, err := fs.ReadFile(fsys, "readme.md")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("readme"))
+	})
+
+	It("should hide whited-out files", func() {
+		_, err := fsys.Open("etc/passwd")
+
+		Expect(err).To(MatchError(fs.ErrNotExist))
+	})
+
+	It("should merge directories without whiteout entries", func() {
+		entries, err := fs.ReadDir(fsys, "etc")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Name()).To(Equal("hosts"))
+	})
+
+	It("should hide lower contents of opaque directories", func() {
+		entries, err := fs.ReadDir(fsys, "var/cache")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Name()).To(Equal("new.txt"))
+	})
+
+	It("should reject invalid paths", func() {
+		_, err := fsys.Open("../etc")
+
+		Expect(err).To(MatchError(fs.ErrInvalid))
+	})
+
+	Describe("Provenance", func() {
+		It("should return the layer that added a file", func() {
+			digest, err := fsys.Provenance("readme.md")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(digest).To(Equal(lowerDigest))
+		})
+
+		It("should return the layer that modified a file", func() {
+			digest, err := fsys.Provenance("etc/hosts")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(digest).To(Equal(upperDigest))
+		})
+
+		It("should return an error for deleted files", func() {
+			_, err := fsys.Provenance("var/cache/old.txt")
+
+			Expect(err).To(MatchError(fs.ErrNotExist))
+		})
+
+		It("should reject invalid paths", func() {
+			_, err := fsys.Provenance("/etc/hosts")
+
+			Expect(err).To(MatchError(fs.ErrInvalid))
+		})
+	})
+
+	Describe("History", func() {
+		It("should record each layer that touched a path", func() {
+			history, err := fsys.History("etc/hosts")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(history).To(Equal([]ctrfs.Change{
+				{Layer: lowerDigest, Type: ctrfs.Added},
+				{Layer: upperDigest, Type: ctrfs.Modified},
+			}))
+		})
+
+		It("should record whiteouts", func() {
+			history, err := fsys.History("etc/passwd")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(history).To(Equal([]ctrfs.Change{
+				{Layer: lowerDigest, Type: ctrfs.Added},
+				{Layer: upperDigest, Type: ctrfs.Deleted},
+			}))
+		})
+
+		It("should record paths hidden by opaque directories", func() {
+			history, err := fsys.History("var/cache/old.txt")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(history).To(HaveLen(2))
+			Expect(history[1].Type).To(Equal(ctrfs.Deleted))
+		})
+
+		It("should return an error for unknown paths", func() {
+			_, err := fsys.History("nonexistent")
+
+			Expect(err).To(MatchError(fs.ErrNotExist))
+		})
+	})
+
+	Describe("fstest", func() {
+		It("should pass fstest.TestFS", func() {
+			err := fstest.TestFS(fsys, "etc/hosts", "readme.md", "var/cache/new.txt")
+
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("FromImageLayers", func() {
+	It("should replace a directory with a file", func() {
+		lower, err := makeLayer([]tarEntry{
+			{hdr: &tar.Header{Name: "app/", Typeflag: tar.TypeDir, Mode: 0755}},
+			{hdr: &tar.Header{Name: "app/main", Typeflag: tar.TypeReg, Size: 4, Mode: 0755}, data: "main"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		upper, err := makeLayer([]tarEntry{
+			{hdr: &tar.Header{Name: "app", Typeflag: tar.TypeReg, Size: 4, Mode: 0644}, data: "file"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		img, err := mutate.AppendLayers(empty.Image, lower, upper)
+		Expect(err).NotTo(HaveOccurred())
+
+		fsys, err := ctrfs.FromImageLayers(img)
+		Expect(err).NotTo(HaveOccurred())
+		defer fsys.Close()
+
+		data, err := fs.ReadFile(fsys, "app")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("file"))
+		_, err = fsys.Provenance("app/main")
+		Expect(err).To(MatchError(fs.ErrNotExist))
+	})
+
+	It("should only delete the subtree of a whited-out directory", func() {
+		lower, err := makeLayer([]tarEntry{
+			{hdr: &tar.Header{Name: "app/", Typeflag: tar.TypeDir, Mode: 0755}},
+			{hdr: &tar.Header{Name: "app/main", Typeflag: tar.TypeReg, Size: 4, Mode: 0755}, data: "main"},
+			{hdr: &tar.Header{Name: "app.txt", Typeflag: tar.TypeReg, Size: 3, Mode: 0644}, data: "app"},
+			{hdr: &tar.Header{Name: "application/", Typeflag: tar.TypeDir, Mode: 0755}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		upper, err := makeLayer([]tarEntry{
+			{hdr: &tar.Header{Name: ".wh.app", Typeflag: tar.TypeReg, Mode: 0644}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		img, err := mutate.AppendLayers(empty.Image, lower, upper)
+		Expect(err).NotTo(HaveOccurred())
+
+		fsys, err := ctrfs.FromImageLayers(img)
+		Expect(err).NotTo(HaveOccurred())
+		defer fsys.Close()
+
+		Expect(fstest.TestFS(fsys, "app.txt", "application")).To(Succeed())
+		for _, name := range []string{"app", "app/main"} {
+			_, err = fsys.Open(name)
+			Expect(err).To(MatchError(fs.ErrNotExist))
+		}
+	})
+
+	It("should read each layer once to build the index", func() {
+		lower, err := makeLayer([]tarEntry{
+			{hdr: &tar.Header{Name: "a.txt", Typeflag: tar.TypeReg, Size: 1, Mode: 0644}, data: "a"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		upper, err := makeLayer([]tarEntry{
+			{hdr: &tar.Header{Name: "b.txt", Typeflag: tar.TypeReg, Size: 1, Mode: 0644}, data: "b"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		counted := []*countingLayer{{Layer: lower}, {Layer: upper}}
+		img, err := mutate.AppendLayers(empty.Image, counted[0], counted[1])
+		Expect(err).NotTo(HaveOccurred())
+
+		fsys, err := ctrfs.FromImageLayers(img)
+		Expect(err).NotTo(HaveOccurred())
+		defer fsys.Close()
+		Expect(counted[0].uncompressed).To(Equal(1))
+		Expect(counted[1].uncompressed).To(Equal(1))
+
+		data, err := fs.ReadFile(fsys, "b.txt")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("b"))
+		Expect(counted[0].uncompressed).To(Equal(1))
+	})
+
+	It("should propagate Digest errors", func() {
+		img, err := mutate.AppendLayers(empty.Image, &errLayer{err: errors.New("digest error")})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = ctrfs.FromImageLayers(img)
+
+		Expect(err).To(MatchError(ContainSubstring("digest error")))
+	})
+})
+
+var _ = Describe("ChangeType", func() {
+	DescribeTable("String",
+		func(c ctrfs.ChangeType, expected string) {
+			Expect(c.String()).To(Equal(expected))
+		},
+		Entry(nil, ctrfs.Added, "added"),
+		Entry(nil, ctrfs.Modified, "modified"),
+		Entry(nil, ctrfs.Deleted, "deleted"),
+		Entry(nil, ctrfs.ChangeType(-1), "unknown"),
+	)
+})
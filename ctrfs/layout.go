@@ -0,0 +1,410 @@
+package ctrfs
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"path"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/unstoppablemango/ihfs"
+)
+
+const (
+	layoutFile  = "oci-layout"
+	indexFile   = "index.json"
+	blobsDir    = "blobs"
+	layoutMagic = `{"imageLayoutVersion": "1.0.0"}`
+)
+
+// FromLayout reads the OCI image layout directory at dir in fsys.
+// Images and nested indexes are loaded lazily from the layout's blobs as they are accessed.
+func FromLayout(fsys ihfs.FS, dir string) (v1.ImageIndex, error) {
+	raw, err := fs.ReadFile(fsys, path.Join(dir, indexFile))
+	if err != nil {
+		return nil, err
+	}
+	return newLayoutIndex(fsys, dir, raw)
+}
+
+// WriteLayout writes img to the OCI image layout directory at dir in fsys,
+// creating the layout if it does not exist and adding img to its index.json.
+// Blobs that already exist in the layout with the expected size are not written again.
+// New blobs and the index are written to a temporary file and renamed into place, and blobs are
+// verified against their digest first, so an interrupted write never leaves a truncated file behind.
+//
+// fsys must support creating directories and files, see [ihfs.MkdirAll], [ihfs.Create] and
+// [ihfs.WriteFile], as well as [ihfs.Rename] and [ihfs.Remove].
+func WriteLayout(fsys ihfs.FS, dir string, img v1.Image) error {
+	if err := ihfs.MkdirAll(fsys, path.Join(dir, blobsDir), 0755); err != nil {
+		return err
+	}
+	if err := writeLayoutFile(fsys, path.Join(dir, layoutFile), bytes.NewBufferString(layoutMagic)); err != nil {
+		return err
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return err
+	}
+	for _, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return err
+		}
+		size, err := layer.Size()
+		if err != nil {
+			return err
+		}
+		if err := writeBlob(fsys, dir, digest, size, layer.Compressed); err != nil {
+			return err
+		}
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return err
+	}
+	config := manifest.Config
+	if err := writeBlob(fsys, dir, config.Digest, config.Size, rawOpener(img.RawConfigFile)); err != nil {
+		return err
+	}
+
+	desc, err := partial.Descriptor(img)
+	if err != nil {
+		return err
+	}
+	if err := writeBlob(fsys, dir, desc.Digest, desc.Size, rawOpener(img.RawManifest)); err != nil {
+		return err
+	}
+
+	return appendManifest(fsys, dir, *desc)
+}
+
+// appendManifest adds desc to the index.json at dir, creating it if necessary.
+func appendManifest(fsys ihfs.FS, dir string, desc v1.Descriptor) error {
+	name := path.Join(dir, indexFile)
+	index := &v1.IndexManifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIImageIndex,
+	}
+
+	if f, err := fsys.Open(name); err == nil {
+		index, err = v1.ParseIndexManifest(f)
+		_ = f.Close()
+		if err != nil {
+			return err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	for _, m := range index.Manifests {
+		if m.Digest == desc.Digest {
+			return nil
+		}
+	}
+	index.Manifests = append(index.Manifests, desc)
+
+	raw, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// The index is written next to its final name and renamed into place, so readers and
+	// interrupted writes never see a partial index.
+	tmp := path.Join(dir, fmt.Sprintf(".tmp-%x-%s", rand.Uint64(), indexFile))
+	if err := writeLayoutFile(fsys, tmp, bytes.NewReader(raw)); err != nil {
+		_ = ihfs.Remove(fsys, tmp)
+		return err
+	}
+	if err := replace(fsys, tmp, name); err != nil {
+		_ = ihfs.Remove(fsys, tmp)
+		return err
+	}
+	return nil
+}
+
+// replace renames tmp to name. File systems that do not rename over existing files
+// have name removed first, which leaves a short window in which it is missing.
+func replace(fsys ihfs.FS, tmp, name string) error {
+	err := ihfs.Rename(fsys, tmp, name)
+	if !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	if err := ihfs.Remove(fsys, name); err != nil {
+		return err
+	}
+	return ihfs.Rename(fsys, tmp, name)
+}
+
+// writeBlob writes the content returned by open to the blob h at dir, unless a blob with the digest h
+// already exists. size is the expected size of the blob, or -1 if it is not known. The content is
+// written to a temporary file in the blobs directory and only renamed into place once it matches h.
+func writeBlob(fsys ihfs.FS, dir string, h v1.Hash, size int64, open func() (io.ReadCloser, error)) error {
+	name := blobPath(dir, h)
+	if ok, err := hasBlob(fsys, name, size); ok || err != nil {
+		return err
+	}
+
+	if err := ihfs.MkdirAll(fsys, path.Dir(name), 0755); err != nil {
+		return err
+	}
+
+	rc, err := open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+
+	hasher, err := v1.Hasher(h.Algorithm)
+	if err != nil {
+		return err
+	}
+	tmp := path.Join(dir, blobsDir, fmt.Sprintf(".tmp-%x", rand.Uint64()))
+	if err := writeLayoutFile(fsys, tmp, io.TeeReader(rc, hasher)); err != nil {
+		_ = ihfs.Remove(fsys, tmp)
+		return err
+	}
+	if got := hex.EncodeToString(hasher.Sum(nil)); got != h.Hex {
+		_ = ihfs.Remove(fsys, tmp)
+		return fmt.Errorf("blob %s: digest mismatch, got %s:%s", h, h.Algorithm, got)
+	}
+	// Some file systems do not rename over existing files, so a bad blob is removed first.
+	if err := ihfs.Remove(fsys, name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		_ = ihfs.Remove(fsys, tmp)
+		return err
+	}
+	if err := ihfs.Rename(fsys, tmp, name); err != nil {
+		_ = ihfs.Remove(fsys, tmp)
+		return err
+	}
+	return nil
+}
+
+// hasBlob reports whether the blob name exists with the given size, if it is known. Blobs are only
+// renamed into place once their digest was verified, so the content is not hashed again. Blobs with
+// the wrong size, e.g. written by an older version, are reported as missing.
+func hasBlob(fsys ihfs.FS, name string, size int64) (bool, error) {
+	info, err := fs.Stat(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return size < 0 || info.Size() == size, nil
+}
+
+// writeLayoutFile creates or truncates name in fsys and copies r into it.
+// If fsys does not implement [ihfs.CreateFS], r is read into memory and written with [ihfs.WriteFile].
+func writeLayoutFile(fsys ihfs.FS, name string, r io.Reader) error {
+	create, ok := fsys.(ihfs.CreateFS)
+	if !ok {
+		return ihfs.WriteReader(fsys, name, r, 0644)
+	}
+
+	f, err := create.Create(name)
+	if err != nil {
+		return err
+	}
+
+	w, ok := f.(io.Writer)
+	if !ok {
+		_ = f.Close()
+		return &fs.PathError{Op: "write", Path: name, Err: ihfs.ErrNotImplemented}
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		_ = f.Close()
+		return &fs.PathError{Op: "write", Path: name, Err: err}
+	}
+	return f.Close()
+}
+
+func rawOpener(raw func() ([]byte, error)) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		b, err := raw()
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+}
+
+func blobPath(dir string, h v1.Hash) string {
+	return path.Join(dir, blobsDir, h.Algorithm, h.Hex)
+}
+
+// layoutIndex is a [v1.ImageIndex] read from an OCI image layout.
+type layoutIndex struct {
+	fsys     ihfs.FS
+	dir      string
+	raw      []byte
+	manifest *v1.IndexManifest
+}
+
+var _ v1.ImageIndex = (*layoutIndex)(nil)
+
+func newLayoutIndex(fsys ihfs.FS, dir string, raw []byte) (*layoutIndex, error) {
+	manifest, err := v1.ParseIndexManifest(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	return &layoutIndex{fsys: fsys, dir: dir, raw: raw, manifest: manifest}, nil
+}
+
+// MediaType implements [v1.ImageIndex].
+func (i *layoutIndex) MediaType() (types.MediaType, error) {
+	if i.manifest.MediaType != "" {
+		return i.manifest.MediaType, nil
+	}
+	return types.OCIImageIndex, nil
+}
+
+// Digest implements [v1.ImageIndex].
+func (i *layoutIndex) Digest() (v1.Hash, error) {
+	return partial.Digest(i)
+}
+
+// Size implements [v1.ImageIndex].
+func (i *layoutIndex) Size() (int64, error) {
+	return partial.Size(i)
+}
+
+// IndexManifest implements [v1.ImageIndex].
+func (i *layoutIndex) IndexManifest() (*v1.IndexManifest, error) {
+	return i.manifest, nil
+}
+
+// RawManifest implements [v1.ImageIndex].
+func (i *layoutIndex) RawManifest() ([]byte, error) {
+	return i.raw, nil
+}
+
+// Image implements [v1.ImageIndex].
+func (i *layoutIndex) Image(h v1.Hash) (v1.Image, error) {
+	desc, err := i.find(h)
+	if err != nil {
+		return nil, err
+	}
+	if !desc.MediaType.IsImage() {
+		return nil, fmt.Errorf("unexpected media type for %v: %s", h, desc.MediaType)
+	}
+
+	raw, err := i.blob(h)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := v1.ParseManifest(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	return partial.CompressedToImage(&layoutImage{
+		index:    i,
+		desc:     *desc,
+		raw:      raw,
+		manifest: manifest,
+	})
+}
+
+// ImageIndex implements [v1.ImageIndex].
+func (i *layoutIndex) ImageIndex(h v1.Hash) (v1.ImageIndex, error) {
+	desc, err := i.find(h)
+	if err != nil {
+		return nil, err
+	}
+	if !desc.MediaType.IsIndex() {
+		return nil, fmt.Errorf("unexpected media type for %v: %s", h, desc.MediaType)
+	}
+
+	raw, err := i.blob(h)
+	if err != nil {
+		return nil, err
+	}
+	return newLayoutIndex(i.fsys, i.dir, raw)
+}
+
+func (i *layoutIndex) find(h v1.Hash) (*v1.Descriptor, error) {
+	for _, desc := range i.manifest.Manifests {
+		if desc.Digest == h {
+			return &desc, nil
+		}
+	}
+	return nil, fmt.Errorf("could not find descriptor in index: %s", h)
+}
+
+func (i *layoutIndex) blob(h v1.Hash) ([]byte, error) {
+	return fs.ReadFile(i.fsys, blobPath(i.dir, h))
+}
+
+// layoutImage is the [partial.CompressedImageCore] of an image read from an OCI image layout.
+type layoutImage struct {
+	index    *layoutIndex
+	desc     v1.Descriptor
+	raw      []byte
+	manifest *v1.Manifest
+}
+
+var _ partial.CompressedImageCore = (*layoutImage)(nil)
+
+// MediaType implements [partial.CompressedImageCore].
+func (i *layoutImage) MediaType() (types.MediaType, error) {
+	return i.desc.MediaType, nil
+}
+
+// RawManifest implements [partial.CompressedImageCore].
+func (i *layoutImage) RawManifest() ([]byte, error) {
+	return i.raw, nil
+}
+
+// RawConfigFile implements [partial.CompressedImageCore].
+func (i *layoutImage) RawConfigFile() ([]byte, error) {
+	return i.index.blob(i.manifest.Config.Digest)
+}
+
+// LayerByDigest implements [partial.CompressedImageCore].
+func (i *layoutImage) LayerByDigest(h v1.Hash) (partial.CompressedLayer, error) {
+	if h == i.manifest.Config.Digest {
+		return &layoutBlob{i.index, i.manifest.Config}, nil
+	}
+	for _, desc := range i.manifest.Layers {
+		if desc.Digest == h {
+			return &layoutBlob{i.index, desc}, nil
+		}
+	}
+	return nil, fmt.Errorf("could not find layer in image: %s", h)
+}
+
+// layoutBlob is a [partial.CompressedLayer] stored in an OCI image layout.
+type layoutBlob struct {
+	index *layoutIndex
+	desc  v1.Descriptor
+}
+
+// Digest implements [partial.CompressedLayer].
+func (b *layoutBlob) Digest() (v1.Hash, error) {
+	return b.desc.Digest, nil
+}
+
+// Compressed implements [partial.CompressedLayer].
+func (b *layoutBlob) Compressed() (io.ReadCloser, error) {
+	return b.index.fsys.Open(blobPath(b.index.dir, b.desc.Digest))
+}
+
+// Size implements [partial.CompressedLayer].
+func (b *layoutBlob) Size() (int64, error) {
+	return b.desc.Size, nil
+}
+
+// MediaType implements [partial.CompressedLayer].
+func (b *layoutBlob) MediaType() (types.MediaType, error) {
+	return b.desc.MediaType, nil
+}
@@ -0,0 +1,203 @@
+package ctrfs_test
+
+import (
+	"io"
+	"io/fs"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/ctrfs"
+	"github.com/unstoppablemango/ihfs/memfs"
+	"github.com/unstoppablemango/ihfs/osfs"
+)
+
+var _ = Describe("Layout", func() {
+	var img v1.Image
+
+	BeforeEach(func() {
+		var err error
+		img, err = ctrfs.ToImage(empty.Image, fstest.MapFS{
+			"etc/os-release": {Data: []byte("ID=test")},
+		}, ".")
+		Expect(err).NotTo(HaveOccurred())
+		img = mutate.MediaType(img, types.OCIManifestSchema1)
+	})
+
+	digest := func(img v1.Image) v1.Hash {
+		GinkgoHelper()
+		h, err := img.Digest()
+		Expect(err).NotTo(HaveOccurred())
+		return h
+	}
+
+	// overwrite replaces the content of name in fsys with data.
+	overwrite := func(fsys *memfs.Fs, name string, data []byte) {
+		GinkgoHelper()
+		f, err := fsys.Create(name)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = f.(io.Writer).Write(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+	}
+
+	It("should round trip an image through memfs", func() {
+		fsys := memfs.New()
+		Expect(ctrfs.WriteLayout(fsys, "layout", img)).To(Succeed())
+
+		index, err := ctrfs.FromLayout(fsys, "layout")
+		Expect(err).NotTo(HaveOccurred())
+		manifest, err := index.IndexManifest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest.Manifests).To(HaveLen(1))
+		Expect(manifest.Manifests[0].Digest).To(Equal(digest(img)))
+
+		loaded, err := index.Image(digest(img))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(digest(loaded)).To(Equal(digest(img)))
+
+		imgFS := ctrfs.FromImage(loaded)
+		defer imgFS.Close()
+		data, err := fs.ReadFile(imgFS, "etc/os-release")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("ID=test"))
+	})
+
+	It("should write the layout marker and blobs", func() {
+		fsys := memfs.New()
+		Expect(ctrfs.WriteLayout(fsys, ".", img)).To(Succeed())
+
+		data, err := fs.ReadFile(fsys, "oci-layout")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("imageLayoutVersion"))
+
+		config, err := img.ConfigName()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = fs.Stat(fsys, "blobs/sha256/"+config.Hex)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should add images to an existing index", func() {
+		other, err := ctrfs.ToImage(img, fstest.MapFS{"app": {Data: []byte("app")}}, ".")
+		Expect(err).NotTo(HaveOccurred())
+		fsys := memfs.New()
+
+		Expect(ctrfs.WriteLayout(fsys, ".", img)).To(Succeed())
+		Expect(ctrfs.WriteLayout(fsys, ".", other)).To(Succeed())
+		Expect(ctrfs.WriteLayout(fsys, ".", img)).To(Succeed())
+
+		index, err := ctrfs.FromLayout(fsys, ".")
+		Expect(err).NotTo(HaveOccurred())
+		manifest, err := index.IndexManifest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest.Manifests).To(HaveLen(2))
+		entries, err := fs.ReadDir(fsys, ".")
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		Expect(names).To(ConsistOf("blobs", "index.json", "oci-layout"), "temporary index files are renamed into place")
+	})
+
+	It("should replace blobs left truncated by an interrupted write", func() {
+		fsys := memfs.New()
+		Expect(ctrfs.WriteLayout(fsys, ".", img)).To(Succeed())
+		layers, err := img.Layers()
+		Expect(err).NotTo(HaveOccurred())
+		layer, err := layers[0].Digest()
+		Expect(err).NotTo(HaveOccurred())
+		name := "blobs/sha256/" + layer.Hex
+		data, err := fs.ReadFile(fsys, name)
+		Expect(err).NotTo(HaveOccurred())
+		overwrite(fsys, name, data[:len(data)/2])
+
+		Expect(ctrfs.WriteLayout(fsys, ".", img)).To(Succeed())
+
+		Expect(fs.ReadFile(fsys, name)).To(Equal(data))
+		entries, err := fs.ReadDir(fsys, "blobs")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+	})
+
+	It("should replace blobs with the wrong content", func() {
+		fsys := memfs.New()
+		config, err := img.ConfigName()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ihfs.MkdirAll(fsys, "blobs/sha256", 0755)).To(Succeed())
+		overwrite(fsys, "blobs/sha256/"+config.Hex, []byte("corrupt"))
+
+		Expect(ctrfs.WriteLayout(fsys, ".", img)).To(Succeed())
+
+		raw, err := img.RawConfigFile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fs.ReadFile(fsys, "blobs/sha256/"+config.Hex)).To(Equal(raw))
+	})
+
+	It("should be readable by go-containerregistry", func() {
+		dir := GinkgoT().TempDir()
+		Expect(ctrfs.WriteLayout(osfs.New(), dir, img)).To(Succeed())
+
+		index, err := layout.ImageIndexFromPath(dir)
+		Expect(err).NotTo(HaveOccurred())
+		loaded, err := index.Image(digest(img))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(digest(loaded)).To(Equal(digest(img)))
+	})
+
+	It("should read layouts written by go-containerregistry", func() {
+		dir := GinkgoT().TempDir()
+		p, err := layout.Write(dir, empty.Index)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.AppendImage(img)).To(Succeed())
+
+		index, err := ctrfs.FromLayout(osfs.New(), dir)
+		Expect(err).NotTo(HaveOccurred())
+		loaded, err := index.Image(digest(img))
+		Expect(err).NotTo(HaveOccurred())
+		layers, err := loaded.Layers()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(layers).To(HaveLen(1))
+	})
+
+	It("should return an error when index.json is missing", func() {
+		_, err := ctrfs.FromLayout(memfs.New(), ".")
+
+		Expect(err).To(MatchError(fs.ErrNotExist))
+	})
+
+	It("should return an error for unknown images", func() {
+		fsys := memfs.New()
+		Expect(ctrfs.WriteLayout(fsys, ".", img)).To(Succeed())
+		index, err := ctrfs.FromLayout(fsys, ".")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = index.Image(v1.Hash{Algorithm: "sha256", Hex: "0000"})
+
+		Expect(err).To(MatchError(ContainSubstring("could not find descriptor")))
+	})
+
+	It("should reject images as indexes", func() {
+		fsys := memfs.New()
+		Expect(ctrfs.WriteLayout(fsys, ".", img)).To(Succeed())
+		index, err := ctrfs.FromLayout(fsys, ".")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = index.ImageIndex(digest(img))
+
+		Expect(err).To(MatchError(ContainSubstring("unexpected media type")))
+	})
+
+	It("should return an error when fsys is not writable", func() {
+		err := ctrfs.WriteLayout(fstest.MapFS{}, ".", img)
+
+		Expect(err).To(HaveOccurred())
+	})
+})
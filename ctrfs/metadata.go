@@ -0,0 +1,85 @@
+package ctrfs
+
+import (
+	"archive/tar"
+	"io/fs"
+
+	"github.com/unstoppablemango/ihfs"
+)
+
+// xattrPrefix is the PAX record prefix used to store extended attributes in tar archives.
+const xattrPrefix = "SCHILY.xattr."
+
+// OwnershipFunc maps the owner of the file at name to the uid and gid written to a layer.
+type OwnershipFunc func(name string, uid, gid int) (int, int)
+
+// WithOwnership rewrites the ownership of every entry written to a layer using fn.
+// It is applied after [WithReproducible], so it can also be used to give reproducible layers a fixed owner.
+func WithOwnership(fn OwnershipFunc) LayerOption {
+	return func(lw *layerWriter) {
+		lw.ownership = fn
+	}
+}
+
+// owner is implemented by the [fs.FileInfo.Sys] values of file systems that track ownership,
+// such as memfs.
+type owner interface {
+	Uid() int
+	Gid() int
+}
+
+// xattrer is implemented by the [fs.FileInfo.Sys] values of file systems that track extended attributes.
+type xattrer interface {
+	Xattrs() map[string]string
+}
+
+// applyMetadata fills in the parts of hdr that [tar.FileInfoHeader] does not read from info.Sys().
+//
+//   - *[tar.Header] values (tarfs) provide device numbers; ownership and xattrs are already copied.
+//   - Values with Uid and Gid methods (memfs) provide ownership.
+//   - Values with an Xattrs method provide extended attributes.
+//   - OS file systems (osfs) provide extended attributes on platforms that support them.
+func applyMetadata(hdr *tar.Header, fsys ihfs.FS, p string, info fs.FileInfo) error {
+	switch sys := info.Sys().(type) {
+	case *tar.Header:
+		hdr.Devmajor, hdr.Devminor = sys.Devmajor, sys.Devminor
+		return nil
+	case owner:
+		hdr.Uid, hdr.Gid = sys.Uid(), sys.Gid()
+	}
+
+	if sys, ok := info.Sys().(xattrer); ok {
+		setXattrs(hdr, sys.Xattrs())
+		return nil
+	}
+
+	xattrs, err := osXattrs(fsys, p, info)
+	if err != nil {
+		return err
+	}
+	setXattrs(hdr, xattrs)
+	return nil
+}
+
+// setXattrs records xattrs in the PAX records of hdr.
+func setXattrs(hdr *tar.Header, xattrs map[string]string) {
+	if len(xattrs) == 0 {
+		return
+	}
+	if hdr.PAXRecords == nil {
+		hdr.PAXRecords = make(map[string]string, len(xattrs))
+	}
+	for k, v := range xattrs {
+		hdr.PAXRecords[xattrPrefix+k] = v
+	}
+}
+
+// applyOwnership rewrites the ownership of hdr, written as name, with fn.
+// The user and group names are cleared since they no longer describe the new ids.
+func applyOwnership(hdr *tar.Header, name string, fn OwnershipFunc) {
+	uid, gid := fn(name, hdr.Uid, hdr.Gid)
+	if uid != hdr.Uid || gid != hdr.Gid {
+		hdr.Uid, hdr.Gid = uid, gid
+		hdr.Uname, hdr.Gname = "", ""
+	}
+}
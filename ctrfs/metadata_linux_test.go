@@ -0,0 +1,44 @@
+//go:build linux
+
+package ctrfs_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/unstoppablemango/ihfs/ctrfs"
+	"github.com/unstoppablemango/ihfs/osfs"
+	"golang.org/x/sys/unix"
+)
+
+var _ = Describe("Layer metadata on linux", func() {
+	It("should write xattrs from osfs", func() {
+		dir := GinkgoT().TempDir()
+		name := filepath.Join(dir, "file.txt")
+		Expect(os.WriteFile(name, []byte("data"), 0644)).To(Succeed())
+		if err := unix.Setxattr(name, "user.ctrfs", []byte("test"), 0); err != nil {
+			if errors.Is(err, syscall.ENOTSUP) || errors.Is(err, syscall.EPERM) {
+				Skip("user xattrs are not supported: " + err.Error())
+			}
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		layer, err := ctrfs.ToLayer(osfs.New(), dir)
+		Expect(err).NotTo(HaveOccurred())
+
+		rc, err := layer.Uncompressed()
+		Expect(err).NotTo(HaveOccurred())
+		defer rc.Close()
+		hdrs, err := tarHeaders(rc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hdrs).To(ContainElement(And(
+			HaveField("Name", "file.txt"),
+			HaveField("PAXRecords", HaveKeyWithValue("SCHILY.xattr.user.ctrfs", "test")),
+		)))
+	})
+})
@@ -0,0 +1,107 @@
+package ctrfs_test
+
+import (
+	"archive/tar"
+	"io"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/unstoppablemango/ihfs/ctrfs"
+	"github.com/unstoppablemango/ihfs/memfs"
+)
+
+var _ = Describe("Layer metadata", func() {
+	headers := func(layer v1.Layer) map[string]*tar.Header {
+		GinkgoHelper()
+		rc, err := layer.Uncompressed()
+		Expect(err).NotTo(HaveOccurred())
+		defer rc.Close()
+
+		hdrs, err := tarHeaders(rc)
+		Expect(err).NotTo(HaveOccurred())
+		result := map[string]*tar.Header{}
+		for _, hdr := range hdrs {
+			result[hdr.Name] = hdr
+		}
+		return result
+	}
+
+	memFS := func() *memfs.Fs {
+		GinkgoHelper()
+		m := memfs.New()
+		Expect(m.Mkdir("home", 0755)).To(Succeed())
+		f, err := m.Create("home/user.txt")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = f.(io.Writer).Write([]byte("user"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+		Expect(m.Chown("home/user.txt", 1000, 100)).To(Succeed())
+		return m
+	}
+
+	It("should write ownership from memfs", func() {
+		layer, err := ctrfs.ToLayer(memFS(), ".")
+		Expect(err).NotTo(HaveOccurred())
+
+		hdr := headers(layer)["home/user.txt"]
+		Expect(hdr).NotTo(BeNil())
+		Expect(hdr.Uid).To(Equal(1000))
+		Expect(hdr.Gid).To(Equal(100))
+	})
+
+	It("should map ownership with WithOwnership", func() {
+		layer, err := ctrfs.ToLayer(memFS(), ".", ctrfs.WithOwnership(func(name string, uid, gid int) (int, int) {
+			if name == "home/user.txt" {
+				return uid + 1, gid + 1
+			}
+			return uid, gid
+		}))
+		Expect(err).NotTo(HaveOccurred())
+
+		hdrs := headers(layer)
+		Expect(hdrs["home/user.txt"].Uid).To(Equal(1001))
+		Expect(hdrs["home/user.txt"].Gid).To(Equal(101))
+		Expect(hdrs["home/"].Uid).To(Equal(0))
+	})
+
+	It("should apply WithOwnership after WithReproducible", func() {
+		layer, err := ctrfs.ToLayer(memFS(), ".",
+			ctrfs.WithReproducible(time.Unix(0, 0)),
+			ctrfs.WithOwnership(func(string, int, int) (int, int) { return 65532, 65532 }),
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(headers(layer)["home/user.txt"].Uid).To(Equal(65532))
+	})
+
+	It("should preserve ownership, devices and xattrs from tar-backed file systems", func() {
+		src, err := makeLayer([]tarEntry{
+			{hdr: &tar.Header{Name: "dev/", Typeflag: tar.TypeDir, Mode: 0755}},
+			{hdr: &tar.Header{Name: "dev/null", Typeflag: tar.TypeChar, Mode: 0666, Devmajor: 1, Devminor: 3}},
+			{hdr: &tar.Header{Name: "dev/fifo", Typeflag: tar.TypeFifo, Mode: 0600}},
+			{hdr: &tar.Header{
+				Name: "bin/ping", Typeflag: tar.TypeReg, Size: 4, Mode: 0755, Uid: 10, Gid: 20,
+				PAXRecords: map[string]string{"SCHILY.xattr.security.capability": "caps"},
+			}, data: "ping"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		fsys, err := ctrfs.FromLayer(src)
+		Expect(err).NotTo(HaveOccurred())
+		defer fsys.Close()
+
+		layer, err := ctrfs.ToLayer(fsys, ".")
+		Expect(err).NotTo(HaveOccurred())
+
+		hdrs := headers(layer)
+		Expect(hdrs["dev/null"].Typeflag).To(Equal(byte(tar.TypeChar)))
+		Expect(hdrs["dev/null"].Devmajor).To(Equal(int64(1)))
+		Expect(hdrs["dev/null"].Devminor).To(Equal(int64(3)))
+		Expect(hdrs["dev/fifo"].Typeflag).To(Equal(byte(tar.TypeFifo)))
+		Expect(hdrs["bin/ping"].Uid).To(Equal(10))
+		Expect(hdrs["bin/ping"].Gid).To(Equal(20))
+		Expect(hdrs["bin/ping"].PAXRecords).To(HaveKeyWithValue("SCHILY.xattr.security.capability", "caps"))
+	})
+})
@@ -0,0 +1,517 @@
+package ctrfs
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/cowfs"
+	"github.com/unstoppablemango/ihfs/memfs"
+)
+
+// MountFS is a writable working copy of a [v1.Image], similar to a container's root file system.
+//
+// Reads are served by a [cowfs.Fs] that layers an in-memory [memfs.Fs] on top of the read-only
+// [ImageFS] of the image. Writes only ever touch the in-memory layer. Deleting a path that exists
+// in the image records an OCI whiteout in the in-memory layer, so [MountFS.Commit] can turn the
+// changes into a new image layer with [ToImage].
+//
+// Call [MountFS.Close] when the FS is no longer needed to release the underlying image stream.
+type MountFS struct {
+	img   v1.Image
+	base  *ImageFS
+	upper *memfs.Fs
+	cow   *cowfs.Fs
+
+	// xattrs holds the extended attributes of entries copied up from the image,
+	// which the in-memory layer cannot store itself.
+	mu     sync.Mutex
+	xattrs map[string]map[string]string
+}
+
+// Mount creates a writable [MountFS] backed by img.
+func Mount(img v1.Image) *MountFS {
+	base := FromImage(img)
+	upper := memfs.New()
+
+	return &MountFS{
+		img:    img,
+		base:   base,
+		upper:  upper,
+		cow:    cowfs.New(statFS{base}, upper, cowfs.WithMergeStrategy(mergeWhiteouts)),
+		xattrs: map[string]map[string]string{},
+	}
+}
+
+// Commit appends the changes made to f as a new layer on top of the mounted image.
+// Options configure how the layer is written, see [ToLayer].
+//
+// Entries copied up from the image keep their owner, modification time and extended attributes.
+// The root directory is left out of the layer, since it cannot be changed through f.
+func (f *MountFS) Commit(options ...LayerOption) (v1.Image, error) {
+	return ToImage(f.img, layerFS{f}, ".", append([]LayerOption{withoutRoot()}, options...)...)
+}
+
+// Close releases the underlying image stream.
+func (f *MountFS) Close() error {
+	return f.base.Close()
+}
+
+// Open implements [fs.FS].
+func (f *MountFS) Open(name string) (ihfs.File, error) {
+	if !fs.ValidPath(name) || isWhiteout(name) {
+		return nil, f.error("open", name, fs.ErrInvalid)
+	}
+	if hidden, err := f.hidden(name); err != nil {
+		return nil, err
+	} else if hidden {
+		return nil, f.error("open", name, fs.ErrNotExist)
+	}
+
+	return f.cow.Open(name)
+}
+
+// Stat implements [fs.StatFS].
+func (f *MountFS) Stat(name string) (ihfs.FileInfo, error) {
+	file, err := f.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	return file.Stat()
+}
+
+// Create implements [ihfs.CreateFS]. The file is created, or truncated, in the in-memory layer.
+// A truncated file keeps its owner and extended attributes.
+func (f *MountFS) Create(name string) (ihfs.File, error) {
+	if err := f.prepare("create", name); err != nil {
+		return nil, err
+	}
+	existing, err := f.Stat(name)
+	if err != nil {
+		existing = nil
+	} else if existing.IsDir() {
+		return nil, f.error("create", name, syscall.EISDIR)
+	}
+	if err := f.unwhiteout(name); err != nil {
+		return nil, err
+	}
+
+	file, err := f.upper.Create(name)
+	if err != nil || existing == nil {
+		return file, err
+	}
+	if err := f.inherit(name, existing, false); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// WriteFile implements [ihfs.WriteFileFS].
+func (f *MountFS) WriteFile(name string, data []byte, perm ihfs.FileMode) error {
+	file, err := f.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := file.(io.Writer).Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return f.upper.Chmod(name, perm)
+}
+
+// Mkdir implements [ihfs.MkdirFS].
+func (f *MountFS) Mkdir(name string, perm ihfs.FileMode) error {
+	if err := f.prepare("mkdir", name); err != nil {
+		return err
+	}
+	if _, err := f.Stat(name); err == nil {
+		return f.error("mkdir", name, fs.ErrExist)
+	}
+	if err := f.unwhiteout(name); err != nil {
+		return err
+	}
+	if err := f.upper.Mkdir(name, perm); err != nil {
+		return err
+	}
+
+	// A directory that was removed from the image and created again starts out empty.
+	// Each child is whited out individually rather than marking the directory opaque,
+	// since not every layer reader understands opaque whiteouts.
+	if isDir, err := f.baseIsDir(name); err != nil || !isDir {
+		return err
+	}
+	entries, err := fs.ReadDir(f.base, name)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := f.whiteout(path.Join(name, whiteoutPrefix+e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MkdirAll implements [ihfs.MkdirAllFS].
+func (f *MountFS) MkdirAll(name string, perm ihfs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return f.error("mkdir", name, fs.ErrInvalid)
+	}
+
+	var dir string
+	for _, part := range strings.Split(name, "/") {
+		dir = path.Join(dir, part)
+		info, err := f.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return f.error("mkdir", dir, syscall.ENOTDIR)
+			}
+			continue
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if err := f.Mkdir(dir, perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove implements [ihfs.RemoveFS]. Directories must be empty.
+func (f *MountFS) Remove(name string) error {
+	if name == "." {
+		return f.error("remove", name, fs.ErrInvalid)
+	}
+	info, err := f.Stat(name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		entries, err := fs.ReadDir(f, name)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return f.error("remove", name, syscall.ENOTEMPTY)
+		}
+	}
+
+	return f.remove(name)
+}
+
+// RemoveAll implements [ihfs.RemoveAllFS].
+func (f *MountFS) RemoveAll(name string) error {
+	if name == "." {
+		return f.error("removeall", name, fs.ErrInvalid)
+	}
+	if _, err := f.Stat(name); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return f.remove(name)
+}
+
+// remove deletes name from the in-memory layer and hides any copy of it in the image.
+func (f *MountFS) remove(name string) error {
+	if err := f.upper.RemoveAll(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	f.mu.Lock()
+	for p := range f.xattrs {
+		if p == name || strings.HasPrefix(p, name+"/") {
+			delete(f.xattrs, p)
+		}
+	}
+	f.mu.Unlock()
+
+	if _, err := fs.Stat(f.base, name); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return f.whiteout(path.Join(path.Dir(name), whiteoutPrefix+path.Base(name)))
+}
+
+// prepare validates name for a write operation and copies its parent directories
+// into the in-memory layer.
+func (f *MountFS) prepare(op, name string) error {
+	if !fs.ValidPath(name) || name == "." || isWhiteout(name) {
+		return f.error(op, name, fs.ErrInvalid)
+	}
+
+	dir := path.Dir(name)
+	info, err := f.Stat(dir)
+	if err != nil {
+		return f.error(op, name, fs.ErrNotExist)
+	}
+	if !info.IsDir() {
+		return f.error(op, name, syscall.ENOTDIR)
+	}
+	return f.upperDir(dir)
+}
+
+// upperDir creates dir and its parents in the in-memory layer with the modes, owners,
+// modification times and extended attributes they have in f.
+func (f *MountFS) upperDir(dir string) error {
+	if dir == "." {
+		return nil
+	}
+	if _, err := f.upper.Stat(dir); err == nil {
+		return nil
+	}
+	if err := f.upperDir(path.Dir(dir)); err != nil {
+		return err
+	}
+
+	info, err := f.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if err := f.upper.Mkdir(dir, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return f.inherit(dir, info, true)
+}
+
+// inherit gives the entry name in the in-memory layer the owner of info, which describes the
+// entry it replaces, and its modification time if times is set. The extended attributes of
+// image entries are recorded so that [MountFS.Commit] can write them to the layer.
+func (f *MountFS) inherit(name string, info ihfs.FileInfo, times bool) error {
+	switch sys := info.Sys().(type) {
+	case *tar.Header:
+		if err := f.upper.Chown(name, sys.Uid, sys.Gid); err != nil {
+			return err
+		}
+		if xattrs := headerXattrs(sys); len(xattrs) > 0 {
+			f.mu.Lock()
+			f.xattrs[name] = xattrs
+			f.mu.Unlock()
+		}
+	case owner:
+		if err := f.upper.Chown(name, sys.Uid(), sys.Gid()); err != nil {
+			return err
+		}
+	}
+
+	if !times {
+		return nil
+	}
+	return f.upper.Chtimes(name, info.ModTime(), info.ModTime())
+}
+
+// whiteout records the whiteout entry name in the in-memory layer.
+func (f *MountFS) whiteout(name string) error {
+	if err := f.upperDir(path.Dir(name)); err != nil {
+		return err
+	}
+	file, err := f.upper.Create(name)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// unwhiteout removes the whiteout entry for name, if there is one.
+func (f *MountFS) unwhiteout(name string) error {
+	err := f.upper.Remove(path.Join(path.Dir(name), whiteoutPrefix+path.Base(name)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// hidden reports whether name exists in the image but was removed from f, either
+// directly, by removing one of its parents, or by replacing a parent with a file.
+func (f *MountFS) hidden(name string) (bool, error) {
+	if name == "." {
+		return false, nil
+	}
+	parts := strings.Split(name, "/")
+	dir := "."
+	for i, part := range parts {
+		if found, err := ihfs.Exists(f.upper, path.Join(dir, whiteoutPrefix+part)); err != nil || found {
+			return found, err
+		}
+		if i == len(parts)-1 {
+			break
+		}
+
+		dir = path.Join(dir, part)
+		info, err := f.upper.Stat(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if !info.IsDir() {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (f *MountFS) baseIsDir(name string) (bool, error) {
+	info, err := fs.Stat(f.base, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return info.IsDir(), nil
+}
+
+func (f *MountFS) error(op, name string, err error) error {
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// mergeWhiteouts is a union.MergeStrategy that applies the whiteout entries
+// of the in-memory layer to the image and hides them from directory listings.
+func mergeWhiteouts(layer, base []ihfs.DirEntry) ([]ihfs.DirEntry, error) {
+	var (
+		result  = make([]ihfs.DirEntry, 0, len(layer)+len(base))
+		seen    = map[string]bool{}
+		removed = map[string]bool{}
+	)
+	for _, e := range layer {
+		if name, ok := strings.CutPrefix(e.Name(), whiteoutPrefix); ok {
+			removed[name] = true
+		} else {
+			result = append(result, e)
+			seen[name] = true
+		}
+	}
+	for _, e := range base {
+		if !seen[e.Name()] && !removed[e.Name()] {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func isWhiteout(name string) bool {
+	return strings.HasPrefix(path.Base(name), whiteoutPrefix)
+}
+
+// headerXattrs returns the extended attributes recorded in the PAX records of hdr.
+func headerXattrs(hdr *tar.Header) map[string]string {
+	xattrs := map[string]string{}
+	for k, v := range hdr.PAXRecords {
+		if name, ok := strings.CutPrefix(k, xattrPrefix); ok {
+			xattrs[name] = v
+		}
+	}
+	return xattrs
+}
+
+// layerFS presents the in-memory layer of a [MountFS] to the layer writer,
+// adding the extended attributes of copied up entries to their [fs.FileInfo.Sys] values.
+type layerFS struct {
+	f *MountFS
+}
+
+// Open implements [fs.FS].
+func (l layerFS) Open(name string) (ihfs.File, error) {
+	return l.f.upper.Open(name)
+}
+
+// Stat implements [fs.StatFS].
+func (l layerFS) Stat(name string) (ihfs.FileInfo, error) {
+	info, err := l.f.upper.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if xattrs := l.lookup(name); xattrs != nil {
+		return xattrInfo{info, xattrs}, nil
+	}
+	return info, nil
+}
+
+// ReadDir implements [fs.ReadDirFS].
+func (l layerFS) ReadDir(name string) ([]ihfs.DirEntry, error) {
+	entries, err := fs.ReadDir(l.f.upper, name)
+	if err != nil {
+		return nil, err
+	}
+	for i, e := range entries {
+		if xattrs := l.lookup(path.Join(name, e.Name())); xattrs != nil {
+			entries[i] = xattrEntry{e, xattrs}
+		}
+	}
+	return entries, nil
+}
+
+func (l layerFS) lookup(name string) map[string]string {
+	l.f.mu.Lock()
+	defer l.f.mu.Unlock()
+	return l.f.xattrs[name]
+}
+
+// xattrEntry is a directory entry whose info carries xattrs.
+type xattrEntry struct {
+	ihfs.DirEntry
+	xattrs map[string]string
+}
+
+// Info implements [fs.DirEntry].
+func (e xattrEntry) Info() (ihfs.FileInfo, error) {
+	info, err := e.DirEntry.Info()
+	if err != nil {
+		return nil, err
+	}
+	return xattrInfo{info, e.xattrs}, nil
+}
+
+// xattrInfo is a file info whose Sys value reports xattrs alongside the owner of the file.
+type xattrInfo struct {
+	ihfs.FileInfo
+	xattrs map[string]string
+}
+
+// Sys implements [fs.FileInfo].
+func (i xattrInfo) Sys() any {
+	sys := xattrSys{xattrs: i.xattrs}
+	if o, ok := i.FileInfo.Sys().(owner); ok {
+		sys.uid, sys.gid = o.Uid(), o.Gid()
+	}
+	return sys
+}
+
+// xattrSys implements owner and xattrer.
+type xattrSys struct {
+	uid, gid int
+	xattrs   map[string]string
+}
+
+func (s xattrSys) Uid() int                  { return s.uid }
+func (s xattrSys) Gid() int                  { return s.gid }
+func (s xattrSys) Xattrs() map[string]string { return s.xattrs }
+
+// statFS adds [fs.StatFS] to a file system that only implements Open, as required by [cowfs.Fs].
+type statFS struct {
+	ihfs.FS
+}
+
+// Stat implements [fs.StatFS].
+func (s statFS) Stat(name string) (ihfs.FileInfo, error) {
+	return fs.Stat(s.FS, name)
+}
@@ -0,0 +1,241 @@
+package ctrfs_test
+
+import (
+	"archive/tar"
+	"io/fs"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/unstoppablemango/ihfs/ctrfs"
+)
+
+var _ = Describe("MountFS", func() {
+	var (
+		img  v1.Image
+		fsys *ctrfs.MountFS
+	)
+
+	BeforeEach(func() {
+		var err error
+		img, err = ctrfs.ToImage(empty.Image, fstest.MapFS{
+			"etc/hosts":         {Data: []byte("hosts"), Mode: 0644},
+			"etc/passwd":        {Data: []byte("passwd"), Mode: 0644},
+			"var/cache/old.txt": {Data: []byte("old"), Mode: 0644},
+			"readme.md":         {Data: []byte("readme"), Mode: 0644},
+		}, ".")
+		Expect(err).NotTo(HaveOccurred())
+
+		fsys = ctrfs.Mount(img)
+		DeferCleanup(fsys.Close)
+	})
+
+	names := func(fsys fs.FS, dir string) []string {
+		GinkgoHelper()
+		entries, err := fs.ReadDir(fsys, dir)
+		Expect(err).NotTo(HaveOccurred())
+		var result []string
+		for _, e := range entries {
+			result = append(result, e.Name())
+		}
+		return result
+	}
+
+	commit := func() *ctrfs.ImageFS {
+		GinkgoHelper()
+		committed, err := fsys.Commit()
+		Expect(err).NotTo(HaveOccurred())
+		layers, err := committed.Layers()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(layers).To(HaveLen(2))
+
+		result := ctrfs.FromImage(committed)
+		DeferCleanup(result.Close)
+		return result
+	}
+
+	It("should read files from the image", func() {
+		data, err := fs.ReadFile(fsys, "etc/hosts")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("hosts"))
+	})
+
+	It("should overwrite files", func() {
+		Expect(fsys.WriteFile("etc/hosts", []byte("updated"), 0644)).To(Succeed())
+
+		data, err := fs.ReadFile(fsys, "etc/hosts")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("updated"))
+	})
+
+	It("should create files in new directories", func() {
+		Expect(fsys.MkdirAll("opt/app", 0755)).To(Succeed())
+		Expect(fsys.WriteFile("opt/app/bin", []byte("bin"), 0755)).To(Succeed())
+
+		data, err := fs.ReadFile(fsys, "opt/app/bin")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("bin"))
+		Expect(names(fsys, ".")).To(ContainElement("opt"))
+	})
+
+	It("should hide removed files", func() {
+		Expect(fsys.Remove("etc/passwd")).To(Succeed())
+
+		_, err := fsys.Open("etc/passwd")
+		Expect(err).To(MatchError(fs.ErrNotExist))
+		Expect(names(fsys, "etc")).To(Equal([]string{"hosts"}))
+	})
+
+	It("should hide the contents of removed directories", func() {
+		Expect(fsys.RemoveAll("var")).To(Succeed())
+
+		_, err := fsys.Open("var/cache/old.txt")
+		Expect(err).To(MatchError(fs.ErrNotExist))
+		Expect(names(fsys, ".")).NotTo(ContainElement("var"))
+	})
+
+	It("should recreate removed directories empty", func() {
+		Expect(fsys.RemoveAll("var/cache")).To(Succeed())
+		Expect(fsys.Mkdir("var/cache", 0755)).To(Succeed())
+
+		Expect(names(fsys, "var/cache")).To(BeEmpty())
+	})
+
+	It("should refuse to remove non-empty directories", func() {
+		Expect(fsys.Remove("etc")).To(MatchError(ContainSubstring("directory not empty")))
+	})
+
+	It("should refuse to create files in removed directories", func() {
+		Expect(fsys.RemoveAll("var")).To(Succeed())
+
+		_, err := fsys.Create("var/new.txt")
+
+		Expect(err).To(MatchError(fs.ErrNotExist))
+	})
+
+	It("should list the root of layers with a ./ entry", func() {
+		layer, err := makeLayer([]tarEntry{
+			{hdr: &tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0755}},
+			{hdr: &tar.Header{Name: "./bin/", Typeflag: tar.TypeDir, Mode: 0755}},
+			{hdr: &tar.Header{Name: "./bin/sh", Typeflag: tar.TypeReg, Mode: 0755, Size: 2}, data: "sh"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		img, err := mutate.AppendLayers(empty.Image, layer)
+		Expect(err).NotTo(HaveOccurred())
+		fsys := ctrfs.Mount(img)
+		DeferCleanup(fsys.Close)
+
+		Expect(names(fsys, ".")).To(Equal([]string{"bin"}))
+		Expect(fstest.TestFS(fsys, "bin/sh")).To(Succeed())
+	})
+
+	It("should reject whiteout names", func() {
+		_, err := fsys.Create("etc/.wh.hosts")
+
+		Expect(err).To(MatchError(fs.ErrInvalid))
+	})
+
+	It("should pass fstest.TestFS after changes", func() {
+		Expect(fsys.WriteFile("etc/hosts", []byte("updated"), 0644)).To(Succeed())
+		Expect(fsys.Remove("etc/passwd")).To(Succeed())
+		Expect(fsys.RemoveAll("var")).To(Succeed())
+
+		Expect(fstest.TestFS(fsys, "etc/hosts", "readme.md")).To(Succeed())
+	})
+
+	Describe("Commit", func() {
+		It("should append a layer with the changes", func() {
+			Expect(fsys.WriteFile("etc/hosts", []byte("updated"), 0644)).To(Succeed())
+			Expect(fsys.WriteFile("new.txt", []byte("new"), 0644)).To(Succeed())
+
+			result := commit()
+
+			data, err := fs.ReadFile(result, "etc/hosts")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("updated"))
+			data, err = fs.ReadFile(result, "new.txt")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("new"))
+			data, err = fs.ReadFile(result, "readme.md")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("readme"))
+		})
+
+		It("should encode deletions as whiteouts", func() {
+			Expect(fsys.Remove("etc/passwd")).To(Succeed())
+			Expect(fsys.RemoveAll("var")).To(Succeed())
+
+			result := commit()
+
+			_, err := fs.Stat(result, "etc/passwd")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+			_, err = fs.Stat(result, "var")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+			Expect(names(result, "etc")).To(Equal([]string{"hosts"}))
+		})
+
+		It("should keep the owner, times and xattrs of entries copied up from the image", func() {
+			mtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			layer, err := makeLayer([]tarEntry{
+				{hdr: &tar.Header{Name: "home/", Typeflag: tar.TypeDir, Mode: 0755}},
+				{hdr: &tar.Header{
+					Name: "home/user/", Typeflag: tar.TypeDir, Mode: 0750,
+					Uid: 1000, Gid: 1000, ModTime: mtime,
+					PAXRecords: map[string]string{"SCHILY.xattr.user.origin": "image"},
+				}},
+				{hdr: &tar.Header{
+					Name: "home/user/.profile", Typeflag: tar.TypeReg, Mode: 0644, Size: 7,
+					Uid: 1000, Gid: 1000, ModTime: mtime,
+				}, data: "profile"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			base, err := mutate.AppendLayers(empty.Image, layer)
+			Expect(err).NotTo(HaveOccurred())
+			mounted := ctrfs.Mount(base)
+			DeferCleanup(mounted.Close)
+
+			Expect(mounted.WriteFile("home/user/notes.txt", []byte("notes"), 0644)).To(Succeed())
+			Expect(mounted.WriteFile("home/user/.profile", []byte("updated"), 0644)).To(Succeed())
+			committed, err := mounted.Commit()
+			Expect(err).NotTo(HaveOccurred())
+
+			layers, err := committed.Layers()
+			Expect(err).NotTo(HaveOccurred())
+			rc, err := layers[len(layers)-1].Uncompressed()
+			Expect(err).NotTo(HaveOccurred())
+			defer rc.Close()
+			hdrs, err := tarHeaders(rc)
+			Expect(err).NotTo(HaveOccurred())
+			byName := map[string]*tar.Header{}
+			for _, hdr := range hdrs {
+				byName[hdr.Name] = hdr
+			}
+
+			Expect(byName).To(HaveLen(4))
+			Expect(byName).To(HaveKey("home/"))
+			Expect(byName).To(HaveKey("home/user/notes.txt"))
+			Expect(byName["home/user/"].Uid).To(Equal(1000))
+			Expect(byName["home/user/"].Gid).To(Equal(1000))
+			Expect(byName["home/user/"].ModTime).To(BeTemporally("==", mtime))
+			Expect(byName["home/user/"].PAXRecords).To(HaveKeyWithValue("SCHILY.xattr.user.origin", "image"))
+			Expect(byName["home/user/.profile"].Uid).To(Equal(1000))
+			Expect(byName["home/user/.profile"].Gid).To(Equal(1000))
+		})
+
+		It("should encode recreated directories as empty", func() {
+			Expect(fsys.RemoveAll("var/cache")).To(Succeed())
+			Expect(fsys.Mkdir("var/cache", 0755)).To(Succeed())
+			Expect(fsys.WriteFile("var/cache/new.txt", []byte("new"), 0644)).To(Succeed())
+
+			result := commit()
+
+			Expect(names(result, "var/cache")).To(Equal([]string{"new.txt"}))
+		})
+	})
+})
@@ -0,0 +1,48 @@
+package ctrfs
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// LayerOption configures how [ToLayer], [ToImage] and [Diff] write layers.
+type LayerOption func(*layerWriter)
+
+// WithReproducible makes layer output depend only on the names, contents and
+// permissions of the input tree, so identical trees produce byte-identical layers.
+//
+// Modification times later than epoch are clamped to epoch, ownership is zeroed,
+// modes are normalized to 0755 or 0644 (0777 for symlinks), entries are written
+// in lexical order and the gzip header is fixed.
+// Use [SourceDateEpoch] to honor the SOURCE_DATE_EPOCH environment variable.
+func WithReproducible(epoch time.Time) LayerOption {
+	return func(lw *layerWriter) {
+		lw.reproducible = true
+		lw.epoch = epoch.Truncate(time.Second)
+	}
+}
+
+// SourceDateEpoch returns the time given by the SOURCE_DATE_EPOCH environment variable,
+// as described at https://reproducible-builds.org/specs/source-date-epoch/.
+// If the variable is unset or empty, the Unix epoch is returned.
+func SourceDateEpoch() (time.Time, error) {
+	v := os.Getenv("SOURCE_DATE_EPOCH")
+	if v == "" {
+		return time.Unix(0, 0), nil
+	}
+
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH: %w", err)
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// withoutRoot leaves the entry for the root directory out of the layer.
+func withoutRoot() LayerOption {
+	return func(lw *layerWriter) {
+		lw.omitRoot = true
+	}
+}
@@ -0,0 +1,179 @@
+package ctrfs_test
+
+import (
+	"archive/tar"
+	"io"
+	"io/fs"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/ctrfs"
+	"github.com/unstoppablemango/ihfs/testfs"
+)
+
+var _ = Describe("WithReproducible", func() {
+	var (
+		epoch time.Time
+		tree  func(mtime time.Time, perm fs.FileMode) fstest.MapFS
+	)
+
+	BeforeEach(func() {
+		epoch = time.Unix(1700000000, 0)
+		tree = func(mtime time.Time, perm fs.FileMode) fstest.MapFS {
+			return fstest.MapFS{
+				"bin":          {Mode: fs.ModeDir | 0700, ModTime: mtime},
+				"bin/app":      {Data: []byte("app"), Mode: perm | 0100, ModTime: mtime},
+				"etc/app.conf": {Data: []byte("conf"), Mode: perm, ModTime: mtime},
+			}
+		}
+	})
+
+	headers := func(layer v1.Layer) []*tar.Header {
+		GinkgoHelper()
+		rc, err := layer.Uncompressed()
+		Expect(err).NotTo(HaveOccurred())
+		defer rc.Close()
+
+		hdrs, err := tarHeaders(rc)
+		Expect(err).NotTo(HaveOccurred())
+		return hdrs
+	}
+
+	digest := func(layer v1.Layer) v1.Hash {
+		GinkgoHelper()
+		h, err := layer.Digest()
+		Expect(err).NotTo(HaveOccurred())
+		return h
+	}
+
+	It("should produce identical layers for identical trees", func() {
+		a, err := ctrfs.ToLayer(tree(time.Now(), 0644), ".", ctrfs.WithReproducible(epoch))
+		Expect(err).NotTo(HaveOccurred())
+		b, err := ctrfs.ToLayer(tree(time.Now().Add(time.Hour), 0600), ".", ctrfs.WithReproducible(epoch))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(digest(a)).To(Equal(digest(b)))
+	})
+
+	It("should differ without the option", func() {
+		a, err := ctrfs.ToLayer(tree(time.Now(), 0644), ".")
+		Expect(err).NotTo(HaveOccurred())
+		b, err := ctrfs.ToLayer(tree(time.Now().Add(time.Hour), 0644), ".")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(digest(a)).NotTo(Equal(digest(b)))
+	})
+
+	It("should clamp modification times to the epoch", func() {
+		layer, err := ctrfs.ToLayer(tree(time.Now(), 0644), ".", ctrfs.WithReproducible(epoch))
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, hdr := range headers(layer) {
+			Expect(hdr.ModTime).NotTo(BeTemporally(">", epoch), hdr.Name)
+			if hdr.Name == "bin/app" {
+				Expect(hdr.ModTime).To(BeTemporally("==", epoch))
+			}
+		}
+	})
+
+	It("should keep modification times before the epoch", func() {
+		mtime := epoch.Add(-time.Hour)
+		layer, err := ctrfs.ToLayer(tree(mtime, 0644), ".", ctrfs.WithReproducible(epoch))
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, hdr := range headers(layer) {
+			if hdr.Name == "bin/app" {
+				Expect(hdr.ModTime).To(BeTemporally("==", mtime))
+			}
+		}
+	})
+
+	It("should normalize modes and ownership", func() {
+		layer, err := ctrfs.ToLayer(tree(time.Now(), 0600), ".", ctrfs.WithReproducible(epoch))
+		Expect(err).NotTo(HaveOccurred())
+
+		modes := map[string]int64{}
+		for _, hdr := range headers(layer) {
+			modes[hdr.Name] = hdr.Mode
+			Expect(hdr.Uid).To(BeZero())
+			Expect(hdr.Gid).To(BeZero())
+			Expect(hdr.Uname).To(BeEmpty())
+			Expect(hdr.Gname).To(BeEmpty())
+		}
+		Expect(modes).To(HaveKeyWithValue("bin/", int64(0755)))
+		Expect(modes).To(HaveKeyWithValue("bin/app", int64(0755)))
+		Expect(modes).To(HaveKeyWithValue("etc/app.conf", int64(0644)))
+	})
+
+	It("should write entries in lexical order", func() {
+		fsys := testfs.New(
+			testfs.WithStat(rootDirStat),
+			testfs.WithReadDir(func(string) ([]ihfs.DirEntry, error) {
+				return []ihfs.DirEntry{
+					testfs.NewDirEntry("b.txt", false),
+					testfs.NewDirEntry("a.txt", false),
+				}, nil
+			}),
+			testfs.WithOpen(func(string) (ihfs.File, error) {
+				return &testfs.File{
+					ReadFunc:  func([]byte) (int, error) { return 0, io.EOF },
+					CloseFunc: func() error { return nil },
+				}, nil
+			}),
+		)
+
+		layer, err := ctrfs.ToLayer(fsys, ".", ctrfs.WithReproducible(epoch))
+		Expect(err).NotTo(HaveOccurred())
+
+		rc, err := layer.Uncompressed()
+		Expect(err).NotTo(HaveOccurred())
+		defer rc.Close()
+		names, err := tarNames(rc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names).To(Equal([]string{"a.txt", "b.txt"}))
+	})
+
+	It("should apply to Diff", func() {
+		lower := fstest.MapFS{"etc/app.conf": {Data: []byte("old")}}
+
+		a, err := ctrfs.Diff(lower, tree(time.Now(), 0644), ".", ctrfs.WithReproducible(epoch))
+		Expect(err).NotTo(HaveOccurred())
+		b, err := ctrfs.Diff(lower, tree(time.Now().Add(time.Hour), 0600), ".", ctrfs.WithReproducible(epoch))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(digest(a)).To(Equal(digest(b)))
+	})
+})
+
+var _ = Describe("SourceDateEpoch", func() {
+	It("should return the Unix epoch when unset", func() {
+		GinkgoT().Setenv("SOURCE_DATE_EPOCH", "")
+
+		t, err := ctrfs.SourceDateEpoch()
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(t).To(BeTemporally("==", time.Unix(0, 0)))
+	})
+
+	It("should parse the environment variable", func() {
+		GinkgoT().Setenv("SOURCE_DATE_EPOCH", "1700000000")
+
+		t, err := ctrfs.SourceDateEpoch()
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(t).To(BeTemporally("==", time.Unix(1700000000, 0)))
+	})
+
+	It("should reject invalid values", func() {
+		GinkgoT().Setenv("SOURCE_DATE_EPOCH", "yesterday")
+
+		_, err := ctrfs.SourceDateEpoch()
+
+		Expect(err).To(MatchError(ContainSubstring("invalid SOURCE_DATE_EPOCH")))
+	})
+})
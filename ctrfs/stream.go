@@ -0,0 +1,145 @@
+package ctrfs
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"sync"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// writeFunc writes a gzip-compressed tar archive to w.
+type writeFunc func(w io.Writer, options ...LayerOption) error
+
+// streamLayer is a [v1.Layer] whose contents are produced on demand by re-running write,
+// so the compressed archive is never held in memory. The digest, diffID and size are
+// computed together in a single pass the first time any of them is needed.
+//
+// The source of write must not change for the lifetime of the layer,
+// otherwise the contents will no longer match the digest.
+type streamLayer struct {
+	write   writeFunc
+	options []LayerOption
+
+	once   sync.Once
+	digest v1.Hash
+	diffID v1.Hash
+	size   int64
+	err    error
+}
+
+func newStreamLayer(write writeFunc, options []LayerOption) *streamLayer {
+	return &streamLayer{write: write, options: options}
+}
+
+// Digest implements [v1.Layer].
+func (l *streamLayer) Digest() (v1.Hash, error) {
+	if err := l.compute(); err != nil {
+		return v1.Hash{}, err
+	}
+	return l.digest, nil
+}
+
+// DiffID implements [v1.Layer].
+func (l *streamLayer) DiffID() (v1.Hash, error) {
+	if err := l.compute(); err != nil {
+		return v1.Hash{}, err
+	}
+	return l.diffID, nil
+}
+
+// Size implements [v1.Layer].
+func (l *streamLayer) Size() (int64, error) {
+	if err := l.compute(); err != nil {
+		return 0, err
+	}
+	return l.size, nil
+}
+
+// MediaType implements [v1.Layer].
+func (l *streamLayer) MediaType() (types.MediaType, error) {
+	return types.DockerLayer, nil
+}
+
+// Compressed implements [v1.Layer]. Each call writes the archive again.
+func (l *streamLayer) Compressed() (io.ReadCloser, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(l.write(pw, l.options...))
+	}()
+	return pr, nil
+}
+
+// Uncompressed implements [v1.Layer].
+func (l *streamLayer) Uncompressed() (io.ReadCloser, error) {
+	rc, err := l.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	gr, err := gzip.NewReader(rc)
+	if err != nil {
+		_ = rc.Close()
+		return nil, err
+	}
+	return &gzipReadCloser{gr, rc}, nil
+}
+
+// compute writes the archive once, hashing the compressed and uncompressed streams as they are produced.
+func (l *streamLayer) compute() error {
+	l.once.Do(func() {
+		var (
+			compressed   = &countingHash{Hash: sha256.New()}
+			uncompressed = sha256.New()
+			options      = append(l.options[:len(l.options):len(l.options)], withUncompressed(uncompressed))
+		)
+		if l.err = l.write(compressed, options...); l.err != nil {
+			return
+		}
+
+		l.digest = sha256Hash(compressed)
+		l.diffID = sha256Hash(uncompressed)
+		l.size = compressed.n
+	})
+	return l.err
+}
+
+// withUncompressed copies the uncompressed tar stream to w as it is written.
+func withUncompressed(w io.Writer) LayerOption {
+	return func(lw *layerWriter) {
+		lw.uncompressed = w
+	}
+}
+
+func sha256Hash(h hash.Hash) v1.Hash {
+	return v1.Hash{
+		Algorithm: "sha256",
+		Hex:       hex.EncodeToString(h.Sum(nil)),
+	}
+}
+
+// countingHash is a [hash.Hash] that counts the bytes written to it.
+type countingHash struct {
+	hash.Hash
+	n int64
+}
+
+func (c *countingHash) Write(p []byte) (int, error) {
+	n, err := c.Hash.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// gzipReadCloser closes both the gzip reader and the underlying compressed stream.
+type gzipReadCloser struct {
+	*gzip.Reader
+	rc io.ReadCloser
+}
+
+func (g *gzipReadCloser) Close() error {
+	return errors.Join(g.Reader.Close(), g.rc.Close())
+}
@@ -0,0 +1,76 @@
+//go:build linux
+
+package ctrfs
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"syscall"
+
+	"github.com/unstoppablemango/ihfs"
+	"golang.org/x/sys/unix"
+)
+
+// osXattrs reads the extended attributes of p when it is a file on an OS file system.
+// File systems that do not support extended attributes report none.
+func osXattrs(fsys ihfs.FS, p string, info fs.FileInfo) (map[string]string, error) {
+	if _, ok := info.Sys().(*syscall.Stat_t); !ok {
+		return nil, nil
+	}
+	if s, ok := fsys.(sortedFS); ok {
+		fsys = s.FS
+	}
+	if _, ok := fsys.(ihfs.OsFS); !ok {
+		return nil, nil
+	}
+
+	names, err := xattrRead(func(dest []byte) (int, error) {
+		return unix.Llistxattr(p, dest)
+	})
+	if errors.Is(err, unix.ENOTSUP) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &fs.PathError{Op: "listxattr", Path: p, Err: err}
+	}
+
+	xattrs := map[string]string{}
+	for _, name := range bytes.Split(names, []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		value, err := xattrRead(func(dest []byte) (int, error) {
+			return unix.Lgetxattr(p, string(name), dest)
+		})
+		if errors.Is(err, unix.ENODATA) {
+			continue
+		}
+		if err != nil {
+			return nil, &fs.PathError{Op: "getxattr", Path: p, Err: err}
+		}
+		xattrs[string(name)] = string(value)
+	}
+	return xattrs, nil
+}
+
+// xattrRead calls read once to size the buffer and again to fill it,
+// retrying if the attribute grew in between.
+func xattrRead(read func(dest []byte) (int, error)) ([]byte, error) {
+	for {
+		n, err := read(nil)
+		if err != nil || n == 0 {
+			return nil, err
+		}
+
+		buf := make([]byte, n)
+		n, err = read(buf)
+		if errors.Is(err, unix.ERANGE) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package ctrfs
+
+import (
+	"io/fs"
+
+	"github.com/unstoppablemango/ihfs"
+)
+
+// osXattrs reports no extended attributes on platforms where they are not supported.
+func osXattrs(ihfs.FS, string, fs.FileInfo) (map[string]string, error) {
+	return nil, nil
+}
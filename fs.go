@@ -222,6 +222,17 @@ type TempFileFS interface {
 	TempFile(dir, pattern string) (name string, err error)
 }
 
+// VersionFS is the interface implemented by a file system that can report an
+// opaque version of a file, such as an ETag or a content hash.
+type VersionFS interface {
+	FS
+
+	// Version returns a token that changes whenever the content of the named
+	// file changes. Tokens are only comparable for the same file system and name.
+	// If there is an error, it should be of type [*PathError].
+	Version(name string) (string, error)
+}
+
 // WriteFileFS is the interface implemented by a file system that supports writing files.
 type WriteFileFS interface {
 	FS
@@ -0,0 +1,79 @@
+package ghfs
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"strings"
+
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/op"
+	"github.com/unstoppablemango/ihfs/tarfs"
+)
+
+// OpenArchive downloads the tarball of owner/repo at ref with a single request and returns it as a
+// read-only filesystem. The top-level "owner-repo-sha" directory of the archive is stripped, so paths
+// are relative to the root of the repository. The archive is streamed and read as files are opened;
+// Close the returned filesystem to release the connection.
+func OpenArchive(fsys ihfs.FS, owner, repo, ref string) (*tarfs.TarFile, error) {
+	name := tarballPath(owner, repo, ref)
+	f, ok := fsys.(*Fs)
+	if !ok {
+		return nil, openErr(name, errNotImplemented)
+	}
+
+	ctx := f.context(op.Open{Name: name})
+	resp, err := get(ctx, f.client, name, "")
+	if err != nil {
+		return nil, openErr(name, notExist(err))
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		_ = resp.Body.Close()
+		return nil, openErr(name, err)
+	}
+
+	r, w := io.Pipe()
+	go func() {
+		err := stripArchiveRoot(w, tar.NewReader(gz))
+		_ = resp.Body.Close()
+		_ = w.CloseWithError(err)
+	}()
+	return tarfs.FromReader(name, r), nil
+}
+
+// stripArchiveRoot copies the archive read by tr to w, removing the first component of each path
+// along with the pax global header GitHub uses to record the commit SHA.
+func stripArchiveRoot(w io.Writer, tr *tar.Reader) error {
+	tw := tar.NewWriter(w)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return tw.Close()
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+
+		_, name, _ := strings.Cut(hdr.Name, "/")
+		if name == "" {
+			continue
+		}
+		hdr.Name = name
+		if hdr.Typeflag == tar.TypeLink {
+			_, hdr.Linkname, _ = strings.Cut(hdr.Linkname, "/")
+		}
+		delete(hdr.PAXRecords, "path")
+		delete(hdr.PAXRecords, "linkpath")
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+}
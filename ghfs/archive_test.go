@@ -0,0 +1,109 @@
+package ghfs_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/ghfs"
+)
+
+// tarball builds a gzipped archive laid out like the GitHub tarball endpoint's,
+// with a pax global header and everything under a single top-level directory.
+func tarball(files map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+
+	headers := []*tar.Header{
+		{Typeflag: tar.TypeXGlobalHeader, Name: "pax_global_header", PAXRecords: map[string]string{"comment": "abc123"}},
+		{Typeflag: tar.TypeDir, Name: "owner-repo-abc123/", Mode: 0o755},
+		{Typeflag: tar.TypeDir, Name: "owner-repo-abc123/cmd/", Mode: 0o755},
+	}
+	for _, hdr := range headers {
+		Expect(tw.WriteHeader(hdr)).To(Succeed())
+	}
+	for name, content := range files {
+		Expect(tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     "owner-repo-abc123/" + name,
+			Mode:     0o644,
+			Size:     int64(len(content)),
+		})).To(Succeed())
+		_, err := tw.Write([]byte(content))
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	Expect(tw.Close()).To(Succeed())
+	Expect(gz.Close()).To(Succeed())
+	return buf.Bytes()
+}
+
+var _ = Describe("OpenArchive", func() {
+	var (
+		requests []string
+		fsys     *ghfs.Fs
+	)
+
+	BeforeEach(func() {
+		requests = nil
+		data := tarball(map[string]string{
+			"README.md":   "# repo",
+			"cmd/main.go": "package main",
+		})
+		fsys = ghfs.New(ghfs.WithClient(testClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.URL.Path)
+			if r.URL.Path != "/repos/owner/repo/tarball/main" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "application/x-gzip")
+			_, _ = w.Write(data)
+		}))))
+	})
+
+	It("should read files relative to the repository root", func() {
+		archive, err := ghfs.OpenArchive(fsys, "owner", "repo", "main")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(archive.Close)
+
+		data, err := fs.ReadFile(archive, "cmd/main.go")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("package main"))
+	})
+
+	It("should list the repository root", func() {
+		archive, err := ghfs.OpenArchive(fsys, "owner", "repo", "main")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(archive.Close)
+
+		var paths []string
+		err = fs.WalkDir(archive, ".", func(path string, d fs.DirEntry, err error) error {
+			paths = append(paths, path)
+			return err
+		})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(paths).To(Equal([]string{".", "README.md", "cmd", "cmd/main.go"}))
+		Expect(requests).To(HaveLen(1))
+	})
+
+	It("should return ErrNotExist for missing refs", func() {
+		_, err := ghfs.OpenArchive(fsys, "owner", "repo", "missing")
+
+		Expect(err).To(MatchError(fs.ErrNotExist))
+	})
+
+	It("should return ErrNotImplemented for non-ghfs FS", func() {
+		_, err := ghfs.OpenArchive(nonGhfsFS{}, "owner", "repo", "main")
+
+		Expect(err).To(MatchError(ihfs.ErrNotImplemented))
+	})
+})
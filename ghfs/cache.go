@@ -0,0 +1,318 @@
+package ghfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/google/go-github/v84/github"
+	"github.com/unstoppablemango/ihfs"
+)
+
+// cacheEntry is the metadata of a cached response. The body is stored next to it.
+type cacheEntry struct {
+	URL          string      `json:"url"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"lastModified,omitempty"`
+	Header       http.Header `json:"header"`
+}
+
+// cacheTransport is an [http.RoundTripper] that caches GET responses with an ETag or Last-Modified
+// header in the directory dir of fsys and revalidates them with conditional requests.
+// GitHub does not count 304 Not Modified responses against the rate limit.
+//
+// Responses are keyed by URL, Accept and Authorization headers, so clients with different tokens
+// never share entries. Range requests and requests that are already conditional are not cached.
+// Only responses from the API host are cached: redirects to release assets, tarballs and zipballs
+// lead to other hosts, and binary bodies served by the API itself are skipped as well.
+type cacheTransport struct {
+	base http.RoundTripper
+	fsys ihfs.FS
+	dir  string
+	host string
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.cacheable(req) {
+		return t.base.RoundTrip(req)
+	}
+
+	key := path.Join(t.dir, cacheKey(req))
+	entry := t.load(key)
+	if entry != nil {
+		req = req.Clone(req.Context())
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && entry != nil:
+		if cached, err := t.cached(key, entry, resp); err == nil {
+			_ = resp.Body.Close()
+			return cached, nil
+		}
+		return resp, nil
+	case resp.StatusCode == http.StatusOK:
+		return t.store(key, resp)
+	default:
+		return resp, nil
+	}
+}
+
+// cached returns the response stored for key, with the headers of the fresh 304 response, such as
+// the rate limit headers, applied on top.
+func (t *cacheTransport) cached(key string, entry *cacheEntry, fresh *http.Response) (*http.Response, error) {
+	body, err := t.fsys.Open(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := entry.Header.Clone()
+	for k, v := range fresh.Header {
+		if k != "Content-Length" {
+			header[k] = v
+		}
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         fresh.Proto,
+		ProtoMajor:    fresh.ProtoMajor,
+		ProtoMinor:    fresh.ProtoMinor,
+		Header:        header,
+		Body:          body,
+		ContentLength: -1,
+		Request:       fresh.Request,
+	}, nil
+}
+
+// store caches the body of resp as it is read, if it can be revalidated later. The body is copied
+// to a temporary file and renamed into place with its metadata once it has been read to the end,
+// and the metadata of an older entry is removed first, so that it is never paired with a partial
+// body. Failing to write the cache does not fail the request.
+func (t *cacheTransport) store(key string, resp *http.Response) (*http.Response, error) {
+	entry := &cacheEntry{
+		URL:          resp.Request.URL.String(),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Header:       resp.Header.Clone(),
+	}
+	if entry.ETag == "" && entry.LastModified == "" {
+		return resp, nil
+	}
+	if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mt == "application/octet-stream" {
+		return resp, nil
+	}
+
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return resp, nil
+	}
+	if err := ihfs.MkdirAll(t.fsys, t.dir, 0755); err != nil {
+		return resp, nil
+	}
+	if err := ihfs.Remove(t.fsys, key+".json"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return resp, nil
+	}
+
+	f, tmp, err := createTemp(t.fsys, key)
+	if err != nil {
+		return resp, nil
+	}
+	w, ok := f.(io.Writer)
+	if !ok {
+		_ = f.Close()
+		_ = ihfs.Remove(t.fsys, tmp)
+		return resp, nil
+	}
+
+	body := &cacheBody{t: t, key: key, tmp: tmp, meta: meta, body: resp.Body, file: f}
+	body.r = io.TeeReader(resp.Body, &cacheWriter{w: w, body: body})
+	resp.Body = body
+	return resp, nil
+}
+
+// commit renames the body written to tmp and then its metadata into place as the entry key.
+func (t *cacheTransport) commit(key, tmp string, meta []byte) error {
+	if err := ihfs.Rename(t.fsys, tmp, key); err != nil {
+		return err
+	}
+	metaTmp := tempName(key + ".json")
+	if err := writeCache(t.fsys, metaTmp, meta); err != nil {
+		return err
+	}
+	return ihfs.Rename(t.fsys, metaTmp, key+".json")
+}
+
+func (t *cacheTransport) load(key string) *cacheEntry {
+	data, err := fs.ReadFile(t.fsys, key+".json")
+	if err != nil {
+		return nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil
+	}
+	return &entry
+}
+
+func (t *cacheTransport) cacheable(req *http.Request) bool {
+	return req.Method == http.MethodGet &&
+		req.URL.Host == t.host &&
+		req.Header.Get("Range") == "" &&
+		req.Header.Get("If-None-Match") == "" &&
+		req.Header.Get("If-Modified-Since") == ""
+}
+
+func cacheKey(req *http.Request) string {
+	h := sha256.New()
+	for _, s := range []string{req.URL.String(), req.Header.Get("Accept"), req.Header.Get("Authorization")} {
+		_, _ = io.WriteString(h, s)
+		_, _ = h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeCache writes data to name in fsys with [ihfs.WriteFileFS] or, failing that, [ihfs.CreateFS].
+func writeCache(fsys ihfs.FS, name string, data []byte) error {
+	if _, ok := fsys.(ihfs.WriteFileFS); ok {
+		return ihfs.WriteFile(fsys, name, data, 0644)
+	}
+
+	f, err := ihfs.Create(fsys, name)
+	if err != nil {
+		return err
+	}
+	w, ok := f.(io.Writer)
+	if !ok {
+		_ = f.Close()
+		return &fs.PathError{Op: "write", Path: name, Err: ihfs.ErrNotImplemented}
+	}
+	if _, err := w.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// tempName returns a unique name next to name to write it to before renaming it into place.
+func tempName(name string) string {
+	return fmt.Sprintf("%s.%x.tmp", name, rand.Uint64())
+}
+
+// createTemp creates a file next to name to write it to before renaming it into place, with
+// [ihfs.CreateTempFS] or, failing that, [ihfs.CreateFS], and returns the file and its name.
+func createTemp(fsys ihfs.FS, name string) (ihfs.File, string, error) {
+	if _, ok := fsys.(ihfs.CreateTempFS); !ok {
+		tmp := tempName(name)
+		f, err := ihfs.Create(fsys, tmp)
+		return f, tmp, err
+	}
+
+	f, err := ihfs.CreateTemp(fsys, path.Dir(name), path.Base(name)+".*.tmp")
+	if err != nil {
+		return nil, "", err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, "", err
+	}
+	return f, path.Join(path.Dir(name), info.Name()), nil
+}
+
+// drainLimit is how much of an unread body is read on Close to complete its cache entry.
+const drainLimit = 64 << 10
+
+// cacheBody is the body of a response that is being cached. What the caller reads is written to a
+// temporary file, which becomes the cache entry if the body is read to the end without errors.
+type cacheBody struct {
+	t    *cacheTransport
+	key  string
+	tmp  string
+	meta []byte
+
+	r    io.Reader
+	body io.ReadCloser
+	file ihfs.File
+	err  error // the first error reading the body or writing the cache
+	eof  bool
+}
+
+// Read implements [io.Reader].
+func (b *cacheBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err == io.EOF {
+		b.eof = true
+	} else if err != nil && b.err == nil {
+		b.err = err
+	}
+	return n, err
+}
+
+// Close implements [io.Closer]. Short remainders of the body are read to complete the cache entry.
+func (b *cacheBody) Close() error {
+	if !b.eof && b.err == nil {
+		_, _ = io.CopyN(io.Discard, b, drainLimit)
+	}
+	err := b.body.Close()
+
+	if ferr := b.file.Close(); ferr != nil && b.err == nil {
+		b.err = ferr
+	}
+	if !b.eof || b.err != nil || b.t.commit(b.key, b.tmp, b.meta) != nil {
+		_ = ihfs.Remove(b.t.fsys, b.tmp)
+	}
+	return err
+}
+
+// cacheWriter writes to the cache file of body, recording the first error in body
+// rather than returning it, so that failing to write the cache does not fail reads.
+type cacheWriter struct {
+	w    io.Writer
+	body *cacheBody
+}
+
+// Write implements [io.Writer].
+func (w *cacheWriter) Write(p []byte) (int, error) {
+	if w.body.err == nil {
+		if _, err := w.w.Write(p); err != nil {
+			w.body.err = err
+		}
+	}
+	return len(p), nil
+}
+
+// withCache returns a copy of c that caches responses in the directory dir of fsys.
+func withCache(c *github.Client, fsys ihfs.FS, dir string) *github.Client {
+	hc := c.Client()
+	base := hc.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	hc.Transport = &cacheTransport{base: base, fsys: fsys, dir: dir, host: c.BaseURL.Host}
+
+	cached := github.NewClient(hc)
+	cached.BaseURL, cached.UploadURL, cached.UserAgent = c.BaseURL, c.UploadURL, c.UserAgent
+	return cached
+}
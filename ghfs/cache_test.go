@@ -0,0 +1,186 @@
+package ghfs_test
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/unstoppablemango/ihfs/ghfs"
+	"github.com/unstoppablemango/ihfs/memfs"
+	"github.com/unstoppablemango/ihfs/osfs"
+)
+
+var _ = Describe("WithCache", func() {
+	var (
+		requests    []*http.Request
+		notModified int
+		body        string
+		handler     http.Handler
+	)
+
+	BeforeEach(func() {
+		requests, notModified, body = nil, 0, `{"name": "test-user"}`
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				notModified++
+				w.Header().Set("X-RateLimit-Remaining", "59")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(body))
+		})
+	})
+
+	readTwice := func(fsys fs.FS) {
+		GinkgoHelper()
+		for range 2 {
+			data, err := fs.ReadFile(fsys, "users/test-user")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal(body))
+		}
+	}
+
+	It("should revalidate cached responses", func() {
+		cache := memfs.New()
+		fsys := ghfs.New(ghfs.WithClient(testClient(handler)), ghfs.WithCache(cache, "."))
+
+		readTwice(fsys)
+
+		Expect(requests).To(HaveLen(2))
+		Expect(requests[0].Header.Get("If-None-Match")).To(BeEmpty())
+		Expect(requests[1].Header.Get("If-None-Match")).To(Equal(`"v1"`))
+		Expect(notModified).To(Equal(1))
+	})
+
+	It("should revalidate with Last-Modified", func() {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r)
+			if r.Header.Get("If-Modified-Since") != "" {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+			_, _ = w.Write([]byte(body))
+		})
+		fsys := ghfs.New(ghfs.WithClient(testClient(handler)), ghfs.WithCache(memfs.New(), "cache"))
+
+		readTwice(fsys)
+
+		Expect(requests[1].Header.Get("If-Modified-Since")).To(Equal("Mon, 02 Jan 2006 15:04:05 GMT"))
+	})
+
+	It("should persist the cache in osfs", func() {
+		dir := GinkgoT().TempDir()
+		client := testClient(handler)
+
+		readTwice(ghfs.New(ghfs.WithClient(client), ghfs.WithCache(osfs.New(), dir)))
+		readTwice(ghfs.New(ghfs.WithClient(client), ghfs.WithCache(osfs.New(), dir)))
+
+		Expect(requests).To(HaveLen(4))
+		Expect(notModified).To(Equal(3))
+	})
+
+	It("should not share entries between tokens", func() {
+		cache := memfs.New()
+		client := testClient(handler)
+
+		readTwice(ghfs.New(ghfs.WithClient(client), ghfs.WithCache(cache, "."), ghfs.WithAuthToken("a")))
+		_, err := fs.ReadFile(ghfs.New(ghfs.WithClient(client), ghfs.WithCache(cache, "."), ghfs.WithAuthToken("b")), "users/test-user")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(requests).To(HaveLen(3))
+		Expect(requests[2].Header.Get("Authorization")).To(Equal("Bearer b"))
+		Expect(requests[2].Header.Get("If-None-Match")).To(BeEmpty())
+	})
+
+	It("should drop the old entry when a new response is not read to the end", func() {
+		truncate := false
+		v1 := handler
+		cache := memfs.New()
+		fsys := ghfs.New(ghfs.WithCache(cache, "."), ghfs.WithClient(testClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !truncate {
+				v1.ServeHTTP(w, r)
+				return
+			}
+			requests = append(requests, r)
+			w.Header().Set("ETag", `"v2"`)
+			w.Header().Set("Content-Length", "100")
+			_, _ = w.Write([]byte(`{"name":`))
+		}))))
+		readTwice(fsys)
+		truncate = true
+
+		_, err := fs.ReadFile(fsys, "users/test-user")
+		Expect(err).To(HaveOccurred())
+		_, err = fs.ReadFile(fsys, "users/test-user")
+		Expect(err).To(HaveOccurred())
+
+		Expect(requests).To(HaveLen(4))
+		Expect(requests[2].Header.Get("If-None-Match")).To(Equal(`"v1"`))
+		Expect(requests[3].Header.Get("If-None-Match")).To(BeEmpty())
+		entries, err := fs.ReadDir(cache, ".")
+		Expect(err).NotTo(HaveOccurred())
+		for _, e := range entries {
+			Expect(e.Name()).NotTo(HaveSuffix(".json"))
+			Expect(e.Name()).NotTo(HaveSuffix(".tmp"))
+		}
+	})
+
+	It("should not cache binary downloads", func() {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r)
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write([]byte(body))
+		})
+		cache := memfs.New()
+		fsys := ghfs.New(ghfs.WithClient(testClient(handler)), ghfs.WithCache(cache, "."))
+
+		readTwice(fsys)
+
+		Expect(requests[1].Header.Get("If-None-Match")).To(BeEmpty())
+		entries, err := fs.ReadDir(cache, ".")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("should not cache responses redirected to other hosts", func() {
+		other := httptest.NewServer(handler)
+		DeferCleanup(other.Close)
+		redirect := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, other.URL+r.URL.Path, http.StatusFound)
+		})
+		cache := memfs.New()
+		fsys := ghfs.New(ghfs.WithClient(testClient(redirect)), ghfs.WithCache(cache, "."))
+
+		readTwice(fsys)
+
+		Expect(requests).To(HaveLen(2))
+		Expect(requests[1].Header.Get("If-None-Match")).To(BeEmpty())
+		entries, err := fs.ReadDir(cache, ".")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("should not cache responses without validators", func() {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r)
+			_, _ = w.Write([]byte(body))
+		})
+		cache := memfs.New()
+		fsys := ghfs.New(ghfs.WithClient(testClient(handler)), ghfs.WithCache(cache, "."))
+
+		readTwice(fsys)
+
+		Expect(requests[1].Header.Get("If-None-Match")).To(BeEmpty())
+		entries, err := fs.ReadDir(cache, ".")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+})
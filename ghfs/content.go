@@ -0,0 +1,164 @@
+package ghfs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"strconv"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// rawMediaType requests the raw content of files from the contents API.
+// Directories are still listed as JSON.
+const rawMediaType = "application/vnd.github.raw+json"
+
+// openContent opens a path from the repository contents API. Directories are returned by the
+// API as a JSON array of their entries, which are read, following pagination, into a directory [File].
+// When raw is true, files are opened with their raw content instead of the JSON content object.
+func openContent(ctx context.Context, c *github.Client, name string, raw bool) (*File, error) {
+	accept := ""
+	if raw {
+		accept = rawMediaType
+	}
+	resp, err := get(ctx, c, name, accept)
+	if err != nil {
+		return nil, err
+	}
+
+	body := bufio.NewReader(resp.Body)
+	if !isListing(resp, body, raw) {
+		size := int64(-1)
+		if raw {
+			size = resp.ContentLength
+		}
+		return &File{
+			name: name,
+			rc:   readCloser{body, resp.Body},
+			size: size,
+			ra:   newRanged(ctx, c, name, accept, size),
+		}, nil
+	}
+
+	entries, err := readEntries(body, resp)
+	for err == nil && resp.NextPage != 0 {
+		var page []fs.DirEntry
+		if resp, err = get(ctx, c, pageURL(name, resp.NextPage), accept); err == nil {
+			page, err = readEntries(resp.Body, resp)
+			entries = append(entries, page...)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{name: name, isDir: true, entries: entries}, nil
+}
+
+// get sends a GET request for url, overriding the default Accept header when accept is not empty.
+func get(ctx context.Context, c *github.Client, url, accept string) (*github.Response, error) {
+	req, err := c.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	return c.BareDo(ctx, req)
+}
+
+// readEntries decodes a page of directory contents from r and closes the response body.
+func readEntries(r io.Reader, resp *github.Response) ([]fs.DirEntry, error) {
+	defer func() { _ = resp.Body.Close() }()
+
+	var contents []*github.RepositoryContent
+	if err := json.NewDecoder(r).Decode(&contents); err != nil {
+		return nil, err
+	}
+
+	entries := make([]fs.DirEntry, 0, len(contents))
+	for _, c := range contents {
+		entries = append(entries, &DirEntry{content: c})
+	}
+	return entries, nil
+}
+
+// isListing reports whether resp is a directory listing. Raw file content may itself
+// look like a JSON array, so raw responses must also be served as JSON.
+func isListing(resp *github.Response, body *bufio.Reader, raw bool) bool {
+	if raw {
+		mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		if err != nil || mediaType != "application/json" {
+			return false
+		}
+	}
+	return isArray(body)
+}
+
+// isArray reports whether the next non-whitespace byte in r starts a JSON array.
+func isArray(r *bufio.Reader) bool {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return false
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			_, _ = r.ReadByte()
+		default:
+			return b[0] == '['
+		}
+	}
+}
+
+func pageURL(name string, page int) string {
+	u, err := url.Parse(name)
+	if err != nil {
+		return name
+	}
+	q := u.Query()
+	q.Set("page", strconv.Itoa(page))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// readCloser reads from a buffered view of a response body and closes the body itself.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// DirEntry is an entry of a repository directory listed by the contents or git trees API.
+type DirEntry struct {
+	content *github.RepositoryContent
+
+	// mode and sys override the mode and [FileInfo.Sys] derived from content, if set.
+	mode fs.FileMode
+	sys  any
+}
+
+func (e *DirEntry) Name() string { return e.content.GetName() }
+func (e *DirEntry) IsDir() bool  { return e.content.GetType() == "dir" }
+
+func (e *DirEntry) Type() fs.FileMode {
+	return e.info().Mode().Type()
+}
+
+func (e *DirEntry) Info() (fs.FileInfo, error) {
+	return e.info(), nil
+}
+
+func (e *DirEntry) info() *FileInfo {
+	fi := contentInfo(e.content)
+	if e.mode != 0 {
+		fi.mode = e.mode
+	}
+	if e.sys != nil {
+		fi.sys = e.sys
+	}
+	return fi
+}
@@ -0,0 +1,175 @@
+package ghfs_test
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/google/go-github/v84/github"
+	"github.com/unstoppablemango/go-github-mock/src/mock"
+	"github.com/unstoppablemango/ihfs/ghfs"
+)
+
+var _ = Describe("Contents", func() {
+	file := func(name string, size int) *github.RepositoryContent {
+		return &github.RepositoryContent{
+			Name: github.Ptr(name),
+			Type: github.Ptr("file"),
+			Size: github.Ptr(size),
+		}
+	}
+
+	dir := func(name string) *github.RepositoryContent {
+		return &github.RepositoryContent{
+			Name: github.Ptr(name),
+			Type: github.Ptr("dir"),
+		}
+	}
+
+	It("should open a directory", func() {
+		c, s := mock.NewMockedHTTPClientAndServer(
+			mock.WithRequestMatch(
+				mock.GetReposContentsByOwnerByRepoByPath,
+				[]*github.RepositoryContent{file("a.txt", 3), dir("sub")},
+			),
+		)
+		DeferCleanup(s.Close)
+		fsys := ghfs.New(ghfs.WithHttpClient(c))
+
+		f, err := fsys.Open("github.com/owner/repo/tree/main/dir")
+		Expect(err).NotTo(HaveOccurred())
+		info, err := f.Stat()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.IsDir()).To(BeTrue())
+
+		entries, err := f.(fs.ReadDirFile).ReadDir(-1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].Name()).To(Equal("a.txt"))
+		Expect(entries[0].IsDir()).To(BeFalse())
+		Expect(entries[1].Name()).To(Equal("sub"))
+		Expect(entries[1].IsDir()).To(BeTrue())
+		Expect(entries[1].Type()).To(Equal(fs.ModeDir))
+
+		info, err = entries[0].Info()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Size()).To(Equal(int64(3)))
+		Expect(info.Mode()).To(Equal(fs.FileMode(0444)))
+	})
+
+	It("should read directory entries in batches", func() {
+		c, s := mock.NewMockedHTTPClientAndServer(
+			mock.WithRequestMatch(
+				mock.GetReposContentsByOwnerByRepoByPath,
+				[]*github.RepositoryContent{file("a.txt", 1), file("b.txt", 1), file("c.txt", 1)},
+			),
+		)
+		DeferCleanup(s.Close)
+		fsys := ghfs.New(ghfs.WithHttpClient(c))
+
+		f, err := fsys.Open("repos/owner/repo/contents/dir?ref=main")
+		Expect(err).NotTo(HaveOccurred())
+		rd := f.(fs.ReadDirFile)
+
+		entries, err := rd.ReadDir(2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(2))
+		entries, err = rd.ReadDir(2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+		entries, err = rd.ReadDir(2)
+		Expect(err).To(Equal(io.EOF))
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("should follow pagination", func() {
+		c, s := mock.NewMockedHTTPClientAndServer(
+			mock.WithRequestMatchPages(
+				mock.GetReposContentsByOwnerByRepoByPath,
+				[]*github.RepositoryContent{file("a.txt", 1)},
+				[]*github.RepositoryContent{file("b.txt", 1)},
+			),
+		)
+		DeferCleanup(s.Close)
+		fsys := ghfs.New(ghfs.WithHttpClient(c))
+
+		entries, err := fs.ReadDir(fsys, "github.com/owner/repo/tree/main/dir")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[1].Name()).To(Equal("b.txt"))
+	})
+
+	It("should return an error when a page fails", func() {
+		c, s := mock.NewMockedHTTPClientAndServer(
+			mock.WithRequestMatchHandler(
+				mock.GetReposContentsByOwnerByRepoByPath,
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Query().Get("page") != "" {
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+					w.Header().Set("Link", `<`+r.URL.Path+`?page=2>; rel="next"`)
+					_, _ = w.Write([]byte(`[{"name": "a.txt", "type": "file"}]`))
+				}),
+			),
+		)
+		DeferCleanup(s.Close)
+		fsys := ghfs.New(ghfs.WithHttpClient(c), ghfs.WithRetries(0, 0))
+
+		_, err := fsys.Open("github.com/owner/repo/tree/main/dir")
+
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should read files", func() {
+		c, s := mock.NewMockedHTTPClientAndServer(
+			mock.WithRequestMatchHandler(
+				mock.GetReposContentsByOwnerByRepoByPath,
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					_, _ = w.Write([]byte(`  {"name": "file.txt", "type": "file"}`))
+				}),
+			),
+		)
+		DeferCleanup(s.Close)
+		fsys := ghfs.New(ghfs.WithHttpClient(c))
+
+		content, err := ghfs.OpenContent(fsys, "owner", "repo", "main", []string{"file.txt"})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(content.GetName()).To(Equal("file.txt"))
+	})
+
+	It("should walk a repository directory", func() {
+		c, s := mock.NewMockedHTTPClientAndServer(
+			mock.WithRequestMatchHandler(
+				mock.GetReposContentsByOwnerByRepoByPath,
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch strings.TrimPrefix(r.URL.Path, "/repos/owner/repo/contents/") {
+					case "dir":
+						_, _ = w.Write([]byte(`[{"name": "a.txt", "type": "file"}, {"name": "sub", "type": "dir"}]`))
+					case "dir/sub":
+						_, _ = w.Write([]byte(`[{"name": "b.txt", "type": "file"}]`))
+					default:
+						_, _ = w.Write([]byte(`{"type": "file"}`))
+					}
+				}),
+			),
+		)
+		DeferCleanup(s.Close)
+		fsys := ghfs.New(ghfs.WithHttpClient(c))
+
+		var paths []string
+		err := fs.WalkDir(fsys, "github.com/owner/repo/tree/main/dir", func(p string, d fs.DirEntry, err error) error {
+			paths = append(paths, strings.TrimPrefix(p, "github.com/owner/repo/tree/main/"))
+			return err
+		})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(paths).To(Equal([]string{"dir", "dir/a.txt", "dir/sub", "dir/sub/b.txt"}))
+	})
+})
@@ -14,6 +14,8 @@
         pname = "ghfs";
         version = "0.0.1";
         src = lib.cleanSource ./.;
+        # Resolves the local replace of the root module in go.mod.
+        pwd = ./.;
         go = pkgs.go_1_26;
         modules = ./gomod2nix.toml;
       };
@@ -4,16 +4,28 @@ import (
 	"encoding/json"
 	"io"
 	"io/fs"
+	"math"
 	"path/filepath"
 	"strings"
 
 	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/internal/httprange"
 )
 
 type File struct {
-	name  string
-	rc    io.ReadCloser
-	isDir bool
+	name    string
+	rc      io.ReadCloser
+	isDir   bool
+	size    int64
+	entries []fs.DirEntry
+
+	// info describes the file when its metadata is known up front.
+	info *FileInfo
+
+	// ra reads the file at an offset, usually with Range requests.
+	// offset is the position of the next Read.
+	ra     io.ReaderAt
+	offset int64
 }
 
 func (f *File) Close() error {
@@ -27,10 +39,83 @@ func (f *File) Read(p []byte) (n int, err error) {
 	if f.isDir {
 		return 0, f.error("read", fs.ErrInvalid)
 	}
+	if f.rc == nil && f.ra != nil {
+		if f.rc, err = f.stream(f.offset); err != nil {
+			return 0, f.error("read", err)
+		}
+	}
 	if f.rc == nil {
 		return 0, nil
 	}
-	return f.rc.Read(p)
+	n, err = f.rc.Read(p)
+	f.offset += int64(n)
+	return n, err
+}
+
+// ReadAt implements [io.ReaderAt]. Files are read with HTTP Range requests, fetching at least
+// a small read-ahead buffer at a time, so e.g. [zip.NewReader] can read a release asset without
+// downloading all of it.
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	if f.isDir || f.ra == nil {
+		return 0, f.error("readat", fs.ErrInvalid)
+	}
+	n, err := f.ra.ReadAt(p, off)
+	if err != nil && err != io.EOF {
+		return n, f.error("readat", err)
+	}
+	return n, err
+}
+
+// Seek implements [io.Seeker]. Reading continues from the initial response until the file is
+// read out of order, after which the next Read requests the rest of the file from the new offset.
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	if f.isDir || f.ra == nil {
+		return 0, f.error("seek", fs.ErrInvalid)
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		size, err := f.fileSize()
+		if err != nil {
+			return 0, f.error("seek", err)
+		}
+		offset += size
+	default:
+		return 0, f.error("seek", fs.ErrInvalid)
+	}
+	if offset < 0 {
+		return 0, f.error("seek", fs.ErrInvalid)
+	}
+
+	if offset != f.offset && f.rc != nil {
+		_ = f.rc.Close()
+		f.rc = nil
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// fileSize returns the size of the file, asking the server if it was not known when it was opened.
+func (f *File) fileSize() (int64, error) {
+	if f.size >= 0 {
+		return f.size, nil
+	}
+	if r, ok := f.ra.(*httprange.Reader); ok {
+		return r.Size()
+	}
+	return 0, httprange.ErrUnknownSize
+}
+
+// stream returns the rest of the file from off. Files read with Range requests are streamed
+// from a single request, rather than a request per read.
+func (f *File) stream(off int64) (io.ReadCloser, error) {
+	if r, ok := f.ra.(*httprange.Reader); ok {
+		return r.Stream(off)
+	}
+	return io.NopCloser(io.NewSectionReader(f.ra, off, math.MaxInt64-off)), nil
 }
 
 func (f *File) ReadDir(n int) ([]fs.DirEntry, error) {
@@ -38,20 +123,34 @@ func (f *File) ReadDir(n int) ([]fs.DirEntry, error) {
 		return nil, f.error("readdir", fs.ErrInvalid)
 	}
 
-	// TODO: implement ReadDir for directories
-	if n > 0 {
+	if n <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	}
+	if len(f.entries) == 0 {
 		return nil, io.EOF
 	}
-	return nil, nil
+
+	n = min(n, len(f.entries))
+	entries := f.entries[:n]
+	f.entries = f.entries[n:]
+	return entries, nil
 }
 
 func (f *File) Stat() (ihfs.FileInfo, error) {
+	if f.info != nil {
+		info := *f.info
+		return &info, nil
+	}
+
 	base, _, _ := strings.Cut(filepath.Base(f.name), "?")
 
 	return &FileInfo{
 		name:  base,
 		rc:    f.rc,
 		isDir: f.isDir,
+		size:  f.size,
 	}, nil
 }
 
@@ -3,21 +3,39 @@ package ghfs
 import (
 	"io"
 	"io/fs"
+	"path"
 	"time"
+
+	"github.com/google/go-github/v84/github"
 )
 
 type FileInfo struct {
-	name  string
-	rc    io.ReadCloser
-	isDir bool
+	name    string
+	rc      io.ReadCloser
+	isDir   bool
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	sys     any
 }
 
 func (fi *FileInfo) Name() string       { return fi.name }
 func (fi *FileInfo) IsDir() bool        { return fi.isDir }
-func (fi *FileInfo) ModTime() time.Time { return time.Time{} }
-func (fi *FileInfo) Sys() any           { return fi.rc }
+func (fi *FileInfo) ModTime() time.Time { return fi.modTime }
+
+// Sys returns the API object describing the file, such as a [github.RepositoryContent],
+// [github.TreeEntry] or [github.ReleaseAsset], when one is known, and otherwise the response body.
+func (fi *FileInfo) Sys() any {
+	if fi.sys != nil {
+		return fi.sys
+	}
+	return fi.rc
+}
 
 func (fi *FileInfo) Mode() fs.FileMode {
+	if fi.mode != 0 {
+		return fi.mode
+	}
 	if fi.isDir {
 		return fs.ModeDir | 0555
 	}
@@ -28,5 +46,38 @@ func (fi *FileInfo) Size() int64 {
 	if fi.isDir {
 		return 0
 	}
-	return -1
+	return fi.size
+}
+
+// contentInfo describes a file, directory, symlink or submodule returned by the contents API.
+// Submodules are reported as irregular files.
+func contentInfo(c *github.RepositoryContent) *FileInfo {
+	fi := &FileInfo{
+		name: c.GetName(),
+		size: int64(c.GetSize()),
+		sys:  c,
+	}
+	if fi.name == "" {
+		fi.name = path.Base(c.GetPath())
+	}
+
+	switch c.GetType() {
+	case "dir":
+		fi.isDir = true
+	case "symlink":
+		fi.mode = fs.ModeSymlink | 0777
+	case "submodule":
+		fi.mode = fs.ModeIrregular | 0555
+	}
+	return fi
+}
+
+// assetInfo describes a release asset. Its ModTime is the time the asset was last updated.
+func assetInfo(a *github.ReleaseAsset) *FileInfo {
+	return &FileInfo{
+		name:    a.GetName(),
+		size:    int64(a.GetSize()),
+		modTime: a.GetUpdatedAt().Time,
+		sys:     a,
+	}
 }
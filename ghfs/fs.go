@@ -6,6 +6,7 @@ import (
 	"io"
 	"io/fs"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v84/github"
 	"github.com/unmango/go/fopt"
@@ -18,24 +19,69 @@ var errNotImplemented = fmt.Errorf("github: %w", ihfs.ErrNotImplemented)
 type ContextFunc func(*Fs, ihfs.Operation) context.Context
 
 type Fs struct {
-	client *github.Client
-	token  string
-	ctxFn  ContextFunc
+	client        *github.Client
+	token         string
+	ctxFn         ContextFunc
+	commit        Commit
+	commitTimes   bool
+	cache         ihfs.FS
+	cacheDir      string
+	hosts         []Host
+	clients       map[Host]*github.Client
+	retries       int
+	backoff       time.Duration
+	wait          bool
+	gistRevisions bool
 }
 
 func New(options ...Option) *Fs {
-	f := &Fs{ctxFn: background}
+	f := &Fs{ctxFn: background, retries: 3, backoff: time.Second}
 	fopt.ApplyAll(f, options)
 	if f.client == nil {
 		f.client = github.NewClient(nil)
 	}
-	if f.token != "" {
-		f.client = f.client.WithAuthToken(f.token)
+
+	f.clients = map[Host]*github.Client{dotcom: f.wrap(f.client)}
+	for _, h := range f.hosts {
+		f.clients[h] = f.wrap(withHost(f.client, h))
+	}
+	f.client = f.clients[dotcom]
+	if len(f.hosts) > 0 {
+		f.client = f.clients[f.hosts[len(f.hosts)-1]]
 	}
 
 	return f
 }
 
+// wrap returns c with the retries, cache and authentication configured for f.
+func (f *Fs) wrap(c *github.Client) *github.Client {
+	c = withRetry(c, retryTransport{
+		retries: f.retries,
+		backoff: f.backoff,
+		wait:    f.wait,
+	})
+	if f.cache != nil {
+		c = withCache(c, f.cache, f.cacheDir)
+	}
+	if f.token != "" {
+		c = c.WithAuthToken(f.token)
+	}
+	return c
+}
+
+// on returns f with the client for the host of p. Paths without a host use the
+// default client, which sends requests to the last host given to [WithHost].
+func (f *Fs) on(p Path) *Fs {
+	c, ok := f.clients[p.origin]
+	if !ok || c == f.client {
+		return f
+	}
+
+	g := *f
+	g.client = c
+	return &g
+}
+
 func (*Fs) Name() string {
 	return "github"
 }
@@ -50,8 +96,10 @@ func (f *Fs) Open(name string) (ihfs.File, error) {
 	return f.open(name)
 }
 
+// context returns the context of op. Rate limits are handled by the retrying transport
+// rather than by the client refusing requests up front.
 func (f *Fs) context(op ihfs.Operation) context.Context {
-	return f.ctxFn(f, op)
+	return context.WithValue(f.ctxFn(f, op), github.BypassRateLimitCheck, true)
 }
 
 func Open(fsys ihfs.FS, name string) (*File, error) {
@@ -62,19 +110,37 @@ func Open(fsys ihfs.FS, name string) (*File, error) {
 }
 
 func (f *Fs) open(name string) (*File, error) {
-	path, err := Parse(name)
+	path, err := parse(name, f.hosts)
 	if err != nil {
 		return nil, openErr(name, err)
 	}
+	f = f.on(path)
 
 	ctx := f.context(op.Open{Name: path.Name()})
-	if id, err := f.assetId(ctx, path); err == nil && id != 0 {
-		return open(ctx, f.client, assetPath(path.owner, path.repo, id))
-	} else if path.asset != "" && err != nil {
+	if path.releases {
+		return f.openReleases(ctx, name, path)
+	}
+	if path.gists {
+		return f.openGist(ctx, name, path)
+	}
+
+	var file *File
+	id, err := f.assetId(ctx, path)
+	switch {
+	case err == nil && id != 0:
+		file, err = open(ctx, f.client, assetPath(path.owner, path.repo, id))
+	case path.asset != "" && err != nil:
+		return nil, openErr(name, err)
+	case len(path.content) > 0:
+		file, err = openContent(ctx, f.client, path.APIPath(), false)
+	default:
+		file, err = open(ctx, f.client, path.APIPath())
+	}
+	if err != nil {
 		return nil, openErr(name, err)
 	}
 
-	return open(ctx, f.client, path.APIPath())
+	return file, nil
 }
 
 func (f *Fs) assetId(ctx context.Context, p Path) (int64, error) {
@@ -86,6 +152,10 @@ func background(*Fs, ihfs.Operation) context.Context {
 }
 
 func release(ctx context.Context, c *github.Client, p Path) (*github.RepositoryRelease, error) {
+	if p.latest {
+		r, _, err := c.Repositories.GetLatestRelease(ctx, p.owner, p.repo)
+		return r, err
+	}
 	if p.releaseID != 0 {
 		r, _, err := c.Repositories.GetRelease(ctx,
 			p.owner, p.repo, p.releaseID,
@@ -127,17 +197,12 @@ func open(ctx context.Context, c *github.Client, url string) (*File, error) {
 	if r, err := do(ctx, c, url); err != nil {
 		return nil, err
 	} else {
-		return &File{name: url, rc: r}, nil
+		return &File{name: url, rc: r, size: -1, ra: newRanged(ctx, c, url, "", -1)}, nil
 	}
 }
 
 func do(ctx context.Context, c *github.Client, url string) (io.ReadCloser, error) {
-	req, err := c.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := c.BareDo(ctx, req)
+	resp, err := get(ctx, c, url, "")
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,118 @@
+package ghfs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"github.com/google/go-github/v84/github"
+	"github.com/unstoppablemango/ihfs"
+)
+
+// openGist opens a path in the browsable gist tree: "{user}/{id}" is a directory of the files of
+// the gist, "{user}/{id}/revisions" is a directory of its revisions, and "{user}/{id}/revisions/{sha}"
+// is a directory of the files of a revision. With [WithGistRevisions], gist directories also list
+// "revisions".
+func (f *Fs) openGist(ctx context.Context, name string, p Path) (*File, error) {
+	if p.revisions && p.gistRevision == "" {
+		entries, err := f.listGistRevisions(ctx, p.gist)
+		if err != nil {
+			return nil, openErr(name, notExist(err))
+		}
+		info := &FileInfo{name: "revisions", isDir: true}
+		return &File{name: name, isDir: true, entries: entries, info: info}, nil
+	}
+
+	var (
+		gist *github.Gist
+		err  error
+	)
+	if p.gistRevision != "" {
+		gist, _, err = f.client.Gists.GetRevision(ctx, p.gist, p.gistRevision)
+	} else {
+		gist, _, err = f.client.Gists.Get(ctx, p.gist)
+	}
+	if err != nil {
+		return nil, openErr(name, notExist(err))
+	}
+
+	if p.gistFile == "" {
+		entries := make([]fs.DirEntry, 0, len(gist.Files)+1)
+		for _, file := range gist.Files {
+			entries = append(entries, fs.FileInfoToDirEntry(gistFileInfo(gist, file)))
+		}
+		if f.gistRevisions && p.gistRevision == "" {
+			entries = append(entries, fs.FileInfoToDirEntry(&FileInfo{name: "revisions", isDir: true}))
+		}
+		slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+			return strings.Compare(a.Name(), b.Name())
+		})
+
+		info := &FileInfo{name: p.gist, isDir: true, modTime: gist.GetUpdatedAt().Time, sys: gist}
+		if p.gistRevision != "" {
+			info.name = p.gistRevision
+		}
+		return &File{name: name, isDir: true, entries: entries, info: info}, nil
+	}
+
+	file, ok := gist.Files[github.GistFilename(p.gistFile)]
+	if !ok {
+		return nil, openErr(name, ihfs.ErrNotExist)
+	}
+	info := gistFileInfo(gist, file)
+
+	// The API truncates the content of large files, which are fetched from their raw URL instead.
+	if file.Content != nil && len(file.GetContent()) >= file.GetSize() {
+		r := strings.NewReader(file.GetContent())
+		return &File{name: name, rc: io.NopCloser(r), size: info.size, info: info, ra: r}, nil
+	}
+	resp, err := get(ctx, f.client, file.GetRawURL(), "")
+	if err != nil {
+		return nil, openErr(name, notExist(err))
+	}
+	return &File{
+		name: name,
+		rc:   resp.Body,
+		size: info.size,
+		info: info,
+		ra:   newRanged(ctx, f.client, file.GetRawURL(), "", info.size),
+	}, nil
+}
+
+// listGistRevisions returns a directory entry for each revision of the gist id, following pagination.
+func (f *Fs) listGistRevisions(ctx context.Context, id string) ([]fs.DirEntry, error) {
+	var (
+		entries []fs.DirEntry
+		opts    = &github.ListOptions{PerPage: 100}
+	)
+	for {
+		commits, resp, err := f.client.Gists.ListCommits(ctx, id, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range commits {
+			entries = append(entries, fs.FileInfoToDirEntry(&FileInfo{
+				name:    c.GetVersion(),
+				isDir:   true,
+				modTime: c.GetCommittedAt().Time,
+				sys:     c,
+			}))
+		}
+		if resp.NextPage == 0 {
+			return entries, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// gistFileInfo describes a file of gist. Its ModTime is the time the gist was last updated.
+func gistFileInfo(gist *github.Gist, file github.GistFile) *FileInfo {
+	return &FileInfo{
+		name:    file.GetFilename(),
+		size:    int64(file.GetSize()),
+		modTime: gist.GetUpdatedAt().Time,
+		sys:     &file,
+	}
+}
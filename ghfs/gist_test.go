@@ -0,0 +1,171 @@
+package ghfs_test
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/google/go-github/v84/github"
+	"github.com/unstoppablemango/ihfs/ghfs"
+)
+
+// gistsHandler serves the gist "abc" with two revisions, "v2" being the latest.
+// The content of large.txt is truncated and served from its raw URL.
+func gistsHandler() http.Handler {
+	updated := &github.Timestamp{Time: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}
+	file := func(name, content string) github.GistFile {
+		return github.GistFile{
+			Filename: github.Ptr(name),
+			Size:     github.Ptr(len(content)),
+			Content:  github.Ptr(content),
+		}
+	}
+	revisions := map[string]*github.Gist{
+		"v1": {
+			ID:        github.Ptr("abc"),
+			UpdatedAt: updated,
+			Files: map[github.GistFilename]github.GistFile{
+				"config.yaml": file("config.yaml", "version: 1"),
+			},
+		},
+		"v2": {
+			ID:        github.Ptr("abc"),
+			UpdatedAt: updated,
+			Files: map[github.GistFilename]github.GistFile{
+				"config.yaml": file("config.yaml", "version: 2"),
+				"large.txt": {
+					Filename: github.Ptr("large.txt"),
+					Size:     github.Ptr(10),
+					Content:  github.Ptr("trunc"),
+					RawURL:   github.Ptr("/raw/abc/large.txt"),
+				},
+			},
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch p := r.URL.Path; {
+		case p == "/gists/abc":
+			_ = json.NewEncoder(w).Encode(revisions["v2"])
+		case p == "/gists/abc/commits":
+			_ = json.NewEncoder(w).Encode([]*github.GistCommit{
+				{Version: github.Ptr("v2"), CommittedAt: updated},
+				{Version: github.Ptr("v1"), CommittedAt: updated},
+			})
+		case p == "/raw/abc/large.txt":
+			_, _ = w.Write([]byte("0123456789"))
+		case strings.HasPrefix(p, "/gists/abc/"):
+			if gist, ok := revisions[strings.TrimPrefix(p, "/gists/abc/")]; ok {
+				_ = json.NewEncoder(w).Encode(gist)
+				return
+			}
+			http.NotFound(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+var _ = Describe("Gists", func() {
+	var fsys *ghfs.Fs
+
+	BeforeEach(func() {
+		fsys = ghfs.New(ghfs.WithClient(testClient(gistsHandler())))
+	})
+
+	It("should list the files of a gist", func() {
+		entries, err := fs.ReadDir(fsys, "gist.github.com/user/abc")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].Name()).To(Equal("config.yaml"))
+		Expect(entries[1].Name()).To(Equal("large.txt"))
+	})
+
+	It("should read gist files", func() {
+		data, err := fs.ReadFile(fsys, "https://gist.github.com/user/abc/config.yaml")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("version: 2"))
+	})
+
+	It("should read truncated gist files from their raw URL", func() {
+		data, err := fs.ReadFile(fsys, "gist.github.com/user/abc/large.txt")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("0123456789"))
+	})
+
+	It("should stat gist files", func() {
+		info, err := fs.Stat(fsys, "gist.github.com/user/abc/config.yaml")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Size()).To(Equal(int64(10)))
+		Expect(info.ModTime()).To(Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)))
+		Expect(info.Sys()).To(BeAssignableToTypeOf(&github.GistFile{}))
+	})
+
+	It("should return ErrNotExist for missing files", func() {
+		_, err := fsys.Open("gist.github.com/user/abc/missing.txt")
+
+		Expect(err).To(MatchError(fs.ErrNotExist))
+	})
+
+	It("should list revisions", func() {
+		entries, err := fs.ReadDir(fsys, "gist.github.com/user/abc/revisions")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].Name()).To(Equal("v1"))
+		Expect(entries[0].IsDir()).To(BeTrue())
+	})
+
+	It("should read files of a revision", func() {
+		data, err := fs.ReadFile(fsys, "gist.github.com/user/abc/revisions/v1/config.yaml")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("version: 1"))
+	})
+
+	It("should list revisions in gist directories with WithGistRevisions", func() {
+		fsys := ghfs.New(ghfs.WithClient(testClient(gistsHandler())), ghfs.WithGistRevisions())
+
+		var paths []string
+		err := fs.WalkDir(fsys, "gist.github.com/user/abc", func(path string, d fs.DirEntry, err error) error {
+			paths = append(paths, strings.TrimPrefix(path, "gist.github.com/user/abc"))
+			return err
+		})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(paths).To(Equal([]string{
+			"",
+			"/config.yaml",
+			"/large.txt",
+			"/revisions",
+			"/revisions/v1",
+			"/revisions/v1/config.yaml",
+			"/revisions/v2",
+			"/revisions/v2/config.yaml",
+			"/revisions/v2/large.txt",
+		}))
+	})
+
+	It("should decode gists", func() {
+		gist, err := ghfs.OpenGist(fsys, "abc")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gist.Files).To(HaveLen(2))
+	})
+
+	It("should decode gist revisions", func() {
+		gist, err := ghfs.OpenGistRevision(fsys, "abc", "v1")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gist.Files).To(HaveKey(github.GistFilename("config.yaml")))
+	})
+})
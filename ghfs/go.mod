@@ -41,3 +41,6 @@ require (
 	golang.org/x/tools v0.42.0 // indirect
 	golang.org/x/tools/go/vcs v0.1.0-deprecated // indirect
 )
+
+// The root module is developed alongside ghfs, which depends on unreleased changes to it.
+replace github.com/unstoppablemango/ihfs => ../
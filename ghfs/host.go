@@ -0,0 +1,105 @@
+package ghfs
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// Host describes a GitHub instance by the addresses of its web interface, REST API, raw content
+// and gists. API, Raw and Gist may include a path prefix, as they do on GitHub Enterprise Server.
+type Host struct {
+	Web  string
+	API  string
+	Raw  string
+	Gist string
+}
+
+// dotcom is github.com, which is always recognized.
+var dotcom = Host{
+	Web:  "github.com",
+	API:  "api.github.com",
+	Raw:  "raw.githubusercontent.com",
+	Gist: "gist.github.com",
+}
+
+// Enterprise returns the addresses of a GitHub Enterprise Server instance at host,
+// which serves its API from "/api/v3", raw content from "/raw" and gists from "/gist".
+func Enterprise(host string) Host {
+	return Host{
+		Web:  host,
+		API:  host + "/api/v3",
+		Raw:  host + "/raw",
+		Gist: host + "/gist",
+	}
+}
+
+type hostKind int
+
+const (
+	unknownHost hostKind = iota
+	apiHost
+	webHost
+	rawHost
+	gistHost
+)
+
+// match reports whether name, without a scheme, is an address of h and returns the rest of name.
+// The longest matching address wins, so that e.g. "ghe.example.com/api/v3/users" is an API path
+// rather than a web path.
+func (h Host) match(name string) (kind hostKind, rest string) {
+	matched := -1
+	for k, addr := range map[hostKind]string{
+		apiHost:  h.API,
+		webHost:  h.Web,
+		rawHost:  h.Raw,
+		gistHost: h.Gist,
+	} {
+		if addr == "" || len(addr) <= matched {
+			continue
+		}
+		r, ok := strings.CutPrefix(name, addr)
+		if ok && (r == "" || r[0] == '/' || r[0] == '?') {
+			kind, rest, matched = k, r, len(addr)
+		}
+	}
+	return kind, rest
+}
+
+// splitHost returns the known host that name is an address of, its host name, the kind of address
+// it is and the path that follows. Names without a host are API paths of no known host.
+func splitHost(name string, u *url.URL, extra []Host) (origin Host, host string, kind hostKind, rest string) {
+	addr := name
+	if u.Host != "" {
+		addr = u.Host + u.RequestURI()
+	}
+
+	for _, h := range append([]Host{dotcom}, extra...) {
+		if kind, rest := h.match(addr); kind != unknownHost {
+			host, _, _ = strings.Cut(addr, "/")
+			return h, host, kind, rest
+		}
+	}
+	if u.Host != "" {
+		return Host{}, u.Hostname(), unknownHost, u.RequestURI()
+	}
+
+	return Host{}, "", apiHost, name
+}
+
+// withHost returns a copy of c that sends API requests to h.
+func withHost(c *github.Client, h Host) *github.Client {
+	web, _, _ := strings.Cut(h.Web, "/")
+	apiHost, apiPath, _ := strings.Cut(h.API, "/")
+
+	hc := github.NewClient(c.Client())
+	hc.BaseURL = &url.URL{Scheme: "https", Host: apiHost, Path: "/" + strings.Trim(apiPath, "/") + "/"}
+	hc.UploadURL = &url.URL{Scheme: "https", Host: web, Path: "/api/uploads/"}
+	if apiPath == "" {
+		hc.BaseURL.Path = "/"
+		hc.UploadURL.Host = "uploads." + web
+	}
+	hc.UserAgent = c.UserAgent
+	return hc
+}
@@ -0,0 +1,108 @@
+package ghfs_test
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/unstoppablemango/ihfs/ghfs"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
+
+var _ = Describe("Host", func() {
+	var requests []string
+
+	BeforeEach(func() {
+		requests = nil
+	})
+
+	client := func() *http.Client {
+		return &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			requests = append(requests, r.URL.String())
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": {"text/plain"}},
+				Body:       io.NopCloser(strings.NewReader("content")),
+				Request:    r,
+			}, nil
+		})}
+	}
+
+	It("should send requests for enterprise paths to the enterprise API", func() {
+		fsys := ghfs.New(
+			ghfs.WithHttpClient(client()),
+			ghfs.WithHost(ghfs.Enterprise("ghe.example.com")),
+		)
+
+		data, err := fs.ReadFile(fsys, "ghe.example.com/owner/repo/blob/main/README.md")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("content"))
+		Expect(requests).To(Equal([]string{
+			"https://ghe.example.com/api/v3/repos/owner/repo/contents/README.md?ref=main",
+		}))
+	})
+
+	It("should use the enterprise API for repository filesystems", func() {
+		fsys := ghfs.Repo("owner", "repo", "main",
+			ghfs.WithHttpClient(client()),
+			ghfs.WithHost(ghfs.Enterprise("ghe.example.com")),
+		)
+
+		_, err := fs.ReadFile(fsys, "go.mod")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0]).To(HavePrefix("https://ghe.example.com/api/v3/repos/owner/repo/contents/go.mod"))
+	})
+
+	It("should send each path to the API of its own host", func() {
+		fsys := ghfs.New(
+			ghfs.WithHttpClient(client()),
+			ghfs.WithHost(ghfs.Enterprise("ghe.example.com")),
+			ghfs.WithHost(ghfs.Enterprise("ghe.example.org")),
+		)
+
+		for _, name := range []string{
+			"github.com/owner/repo/blob/main/README.md",
+			"ghe.example.com/owner/repo/blob/main/README.md",
+			"https://ghe.example.org/owner/repo/blob/main/README.md",
+		} {
+			_, err := fs.ReadFile(fsys, name)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		Expect(requests).To(Equal([]string{
+			"https://api.github.com/repos/owner/repo/contents/README.md?ref=main",
+			"https://ghe.example.com/api/v3/repos/owner/repo/contents/README.md?ref=main",
+			"https://ghe.example.org/api/v3/repos/owner/repo/contents/README.md?ref=main",
+		}))
+	})
+
+	It("should support hosts with a separate API domain", func() {
+		fsys := ghfs.New(
+			ghfs.WithHttpClient(client()),
+			ghfs.WithHost(ghfs.Host{
+				Web: "git.example.com",
+				API: "api.git.example.com",
+				Raw: "raw.git.example.com",
+			}),
+		)
+
+		_, err := fs.ReadFile(fsys, "https://raw.git.example.com/owner/repo/main/README.md")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(Equal([]string{
+			"https://api.git.example.com/repos/owner/repo/contents/README.md?ref=main",
+		}))
+	})
+})
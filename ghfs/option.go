@@ -2,8 +2,10 @@ package ghfs
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/google/go-github/v84/github"
+	"github.com/unstoppablemango/ihfs"
 )
 
 type Option func(*Fs)
@@ -29,3 +31,68 @@ func WithAuthToken(token string) Option {
 		f.token = token
 	}
 }
+
+// WithCommit sets the default commit message and author of writes.
+// A [Commit] returned by [CommitFromContext] takes precedence.
+func WithCommit(c Commit) Option {
+	return func(f *Fs) {
+		f.commit = c
+	}
+}
+
+// WithCommitTimes fills in the ModTime of repository files and directories returned by Stat with the
+// date of the last commit that changed them. This costs an extra request per Stat.
+func WithCommitTimes() Option {
+	return func(f *Fs) {
+		f.commitTimes = true
+	}
+}
+
+// WithHost recognizes the web, API and raw content addresses of h in paths, in addition to those of
+// github.com, and sends API requests for them to h. Use [Enterprise] for a GitHub Enterprise Server instance:
+//
+//	fsys := ghfs.New(ghfs.WithHost(ghfs.Enterprise("ghe.example.com")))
+//	f, err := fsys.Open("ghe.example.com/owner/repo/blob/main/README.md")
+//
+// Each path is sent to the API of its own host, so github.com paths still reach github.com.
+// Paths without a host, such as those of [Repo], are sent to the last host given.
+func WithHost(h Host) Option {
+	return func(f *Fs) {
+		f.hosts = append(f.hosts, h)
+	}
+}
+
+// WithRetries retries requests that fail with a secondary rate limit, and reads that fail with a 5xx, up to n times,
+// waiting backoff before the first retry and doubling it after each one. A Retry-After header
+// from GitHub extends the wait. The default is 3 retries with a backoff of one second; 0 disables retries.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(f *Fs) {
+		f.retries, f.backoff = n, backoff
+	}
+}
+
+// WithRateLimitWait blocks requests that exceed the primary rate limit until it resets,
+// instead of failing with a [RateLimitError].
+func WithRateLimitWait() Option {
+	return func(f *Fs) {
+		f.wait = true
+	}
+}
+
+// WithGistRevisions lists a "revisions" directory in gist directories, with a subdirectory
+// for each revision of the gist. Walking a gist then fetches every revision.
+func WithGistRevisions() Option {
+	return func(f *Fs) {
+		f.gistRevisions = true
+	}
+}
+
+// WithCache caches API responses in the directory dir of fsys, e.g. a memfs or osfs, and
+// revalidates them with conditional requests. Unchanged responses do not count against the rate limit.
+// fsys must support creating, renaming and removing files. Responses are cached once they are read to the end.
+// Downloads of release assets, tarballs and zipballs are not cached.
+func WithCache(fsys ihfs.FS, dir string) Option {
+	return func(f *Fs) {
+		f.cache, f.cacheDir = fsys, dir
+	}
+}
@@ -7,17 +7,14 @@ import (
 	"strings"
 )
 
-var hosts = []string{
-	"github.com",
-	"api.github.com",
-	"raw.githubusercontent.com",
-}
-
 type Path struct {
 	name string
 	u    *url.URL
 	host string
 
+	// origin is the known host that name is an address of, if it has a host.
+	origin Host
+
 	owner   string
 	repo    string
 	branch  string
@@ -26,27 +23,56 @@ type Path struct {
 	tag       string
 	releaseID int64
 
+	// latest is set for the latest release of a repository, as in "releases/latest"
+	// and "releases/latest/download/{asset}".
+	latest bool
+
+	// releases is set for the browsable release tree, where "releases" lists
+	// the tags of a repository and "releases/{tag}" lists the assets of a release.
+	releases bool
+
 	asset   string
 	assetID int64
+
+	// gist is the ID of a gist, and gistRevision the version of a revision of it.
+	// Gist web paths are browsable as a directory of the files of the gist,
+	// with its revisions listed in "revisions".
+	gist         string
+	gistRevision string
+	gistFile     string
+	gists        bool
+	revisions    bool
+}
+
+// Parse parses name as a web, API or raw content URL of github.com, with or without a scheme.
+// Paths without a host are treated as API paths.
+func Parse(name string) (Path, error) {
+	return parse(name, nil)
 }
 
-func Parse(name string) (p Path, err error) {
+// parse is [Parse] with support for the additional hosts registered with [WithHost].
+func parse(name string, extra []Host) (p Path, err error) {
 	p.name = name
 	if p.u, err = url.Parse(name); err != nil {
 		return Path{}, err
 	}
 
-	var path string
-	p.host, path = splitHost(name, p.u)
+	var (
+		path string
+		kind hostKind
+	)
+	p.origin, p.host, kind, path = splitHost(name, p.u, extra)
 	pathOnly, _, _ := strings.Cut(path, "?")
 	parts := strings.Split(strings.TrimLeft(pathOnly, "/"), "/")
-	switch p.host {
-	case "api.github.com", "":
+	switch kind {
+	case apiHost:
 		asAPI(&p, parts)
-	case "github.com":
+	case webHost:
 		asWeb(&p, parts)
-	case "raw.githubusercontent.com":
+	case rawHost:
 		asRaw(&p, parts)
+	case gistHost:
+		asGist(&p, parts)
 	default:
 		return Path{}, fmt.Errorf("invalid host: %s", p.host)
 	}
@@ -58,12 +84,24 @@ func (p Path) Name() string   { return p.name }
 func (p Path) String() string { return p.name }
 
 func (p Path) APIPath() string {
+	if p.gist != "" {
+		if p.gistRevision != "" {
+			return gistRevisionPath(p.gist, p.gistRevision)
+		}
+		return gistPath(p.gist)
+	}
 	if p.owner == "" {
 		return "user"
 	}
 	if p.repo == "" {
 		return ownerPath(p.owner)
 	}
+	if p.releases && p.tag == "" {
+		return releasesPath(p.owner, p.repo)
+	}
+	if p.latest {
+		return latestReleasePath(p.owner, p.repo)
+	}
 	if p.releaseID != 0 {
 		return releasePath(p.owner, p.repo, p.releaseID)
 	}
@@ -80,21 +118,6 @@ func (p Path) APIPath() string {
 	return repoPath(p.owner, p.repo)
 }
 
-func splitHost(name string, u *url.URL) (host, rest string) {
-	if host = u.Hostname(); host != "" {
-		return host, u.RequestURI()
-	}
-
-	for _, h := range hosts {
-		if after, ok := strings.CutPrefix(name, h); ok {
-			p, _, _ := strings.Cut(strings.TrimLeft(after, "/"), "?")
-			return h, p
-		}
-	}
-
-	return "", name
-}
-
 func asWeb(p *Path, parts []string) {
 	if len(parts) > 0 {
 		p.owner = parts[0]
@@ -123,24 +146,55 @@ func asWeb(p *Path, parts []string) {
 			p.content = parts[4:]
 		}
 	case "releases":
-		if len(parts) > 3 && (parts[3] == "tag" || parts[3] == "download") {
-			if len(parts) > 4 {
-				p.tag = parts[4]
-				if id, err := strconv.ParseInt(parts[4], 10, 64); err == nil {
-					p.releaseID = id
-				}
+		if len(parts) > 3 && parts[3] == "latest" {
+			asLatestRelease(p, parts[4:])
+			return
+		}
+		if len(parts) == 3 || (parts[3] != "tag" && parts[3] != "download") {
+			asReleaseTree(p, parts[3:])
+			return
+		}
+		if len(parts) > 4 {
+			p.tag = parts[4]
+			if id, err := strconv.ParseInt(parts[4], 10, 64); err == nil {
+				p.releaseID = id
 			}
-			if len(parts) > 5 {
-				if id, err := strconv.ParseInt(parts[5], 10, 64); err == nil {
-					p.assetID = id
-				} else {
-					p.asset = parts[5]
-				}
+		}
+		if len(parts) > 5 {
+			if id, err := strconv.ParseInt(parts[5], 10, 64); err == nil {
+				p.assetID = id
+			} else {
+				p.asset = parts[5]
 			}
 		}
 	}
 }
 
+// asLatestRelease parses the parts of a web path that follow "releases/latest",
+// which are empty or "download/{asset}".
+func asLatestRelease(p *Path, parts []string) {
+	p.latest = true
+	if len(parts) > 1 && parts[0] == "download" {
+		p.asset = parts[1]
+	}
+}
+
+// asReleaseTree parses the parts of a web path that follow "releases" in the browsable release tree.
+// Tags are path escaped, since they may contain slashes.
+func asReleaseTree(p *Path, parts []string) {
+	p.releases = true
+	if len(parts) > 0 {
+		if tag, err := url.PathUnescape(parts[0]); err == nil {
+			p.tag = tag
+		} else {
+			p.tag = parts[0]
+		}
+	}
+	if len(parts) > 1 {
+		p.asset = parts[1]
+	}
+}
+
 func asRaw(p *Path, parts []string) {
 	if len(parts) > 0 {
 		p.owner = parts[0]
@@ -156,11 +210,51 @@ func asRaw(p *Path, parts []string) {
 	}
 }
 
+// asGist parses a gist web path: {user}/{id}, {user}/{id}/{file}, {user}/{id}/revisions,
+// {user}/{id}/revisions/{sha} or {user}/{id}/revisions/{sha}/{file}.
+func asGist(p *Path, parts []string) {
+	if len(parts) > 0 {
+		p.owner = parts[0]
+	}
+	if len(parts) < 2 {
+		return
+	}
+
+	p.gist, p.gists = parts[1], true
+	if len(parts) < 3 {
+		return
+	}
+	if parts[2] != "revisions" {
+		p.gistFile = parts[2]
+		return
+	}
+
+	p.revisions = true
+	if len(parts) > 3 {
+		p.gistRevision = parts[3]
+	}
+	if len(parts) > 4 {
+		p.gistFile = parts[4]
+	}
+}
+
 func asAPI(p *Path, parts []string) {
 	if len(parts) == 0 || (len(parts) == 1 && parts[0] == "") {
 		return
 	}
 
+	// gists/id
+	// gists/id/sha
+	if parts[0] == "gists" {
+		if len(parts) > 1 {
+			p.gist = parts[1]
+		}
+		if len(parts) > 2 {
+			p.gistRevision = parts[2]
+		}
+		return
+	}
+
 	// users/owner
 	// repos/owner/repo
 	if len(parts) > 1 {
@@ -181,6 +275,8 @@ func asAPI(p *Path, parts []string) {
 		p.branch = parts[4]
 	case "releases":
 		switch parts[4] {
+		case "latest":
+			p.latest = true
 		case "tags":
 			if len(parts) > 5 {
 				p.tag = parts[5]
@@ -223,6 +319,10 @@ func contentPath(owner, repo, branch string, content []string) string {
 	)
 }
 
+func releasesPath(owner, repo string) string {
+	return fmt.Sprintf("repos/%v/%v/releases", owner, repo)
+}
+
 func releasePath(owner, repo string, id int64) string {
 	return fmt.Sprintf(
 		"repos/%v/%v/releases/%v",
@@ -230,6 +330,10 @@ func releasePath(owner, repo string, id int64) string {
 	)
 }
 
+func latestReleasePath(owner, repo string) string {
+	return fmt.Sprintf("repos/%v/%v/releases/latest", owner, repo)
+}
+
 func releasePathByTag(owner, repo, tag string) string {
 	return fmt.Sprintf(
 		"repos/%v/%v/releases/tags/%v",
@@ -237,6 +341,18 @@ func releasePathByTag(owner, repo, tag string) string {
 	)
 }
 
+func tarballPath(owner, repo, ref string) string {
+	return fmt.Sprintf("repos/%v/%v/tarball/%v", owner, repo, ref)
+}
+
+func gistPath(id string) string {
+	return fmt.Sprintf("gists/%v", id)
+}
+
+func gistRevisionPath(id, sha string) string {
+	return fmt.Sprintf("gists/%v/%v", id, sha)
+}
+
 func assetPath(owner, repo string, id int64) string {
 	return fmt.Sprintf("repos/%v/%v/releases/assets/%v", owner, repo, id)
 }
@@ -33,6 +33,11 @@ var _ = Describe("normalize", func() {
 		Entry(nil, "https://github.com/owner/repo/tree/feature%2Fmain", "repos/owner/repo/branches/feature%2Fmain"),
 		Entry(nil, "https://github.com/owner/repo/releases/tag/12345", "repos/owner/repo/releases/12345"),
 		Entry(nil, "https://github.com/owner/repo/releases/tag/v1.0.0/123", "repos/owner/repo/releases/tags/v1.0.0"),
+		Entry(nil, "https://github.com/owner/repo/releases", "repos/owner/repo/releases"),
+		Entry(nil, "https://github.com/owner/repo/releases/v1.0.0", "repos/owner/repo/releases/tags/v1.0.0"),
+		Entry(nil, "https://github.com/owner/repo/releases/v1.0.0/tool.tar.gz", "repos/owner/repo/releases/tags/v1.0.0"),
+		Entry(nil, "https://github.com/owner/repo/releases/latest", "repos/owner/repo/releases/latest"),
+		Entry(nil, "https://github.com/owner/repo/releases/latest/download/tool.tar.gz", "repos/owner/repo/releases/latest"),
 	)
 
 	DescribeTable("raw.githubusercontent.com scheme (raw-style)",
@@ -70,6 +75,7 @@ var _ = Describe("normalize", func() {
 		Entry(nil, "api.github.com/users/test-user", "users/test-user"),
 		Entry(nil, "api.github.com/repos/owner/repo", "repos/owner/repo"),
 		Entry(nil, "api.github.com/repos/owner/repo/releases/1", "repos/owner/repo/releases/1"),
+		Entry(nil, "api.github.com/repos/owner/repo/releases/latest", "repos/owner/repo/releases/latest"),
 	)
 
 	DescribeTable("schemeless raw.githubusercontent.com prefix (raw-style)",
@@ -100,6 +106,44 @@ var _ = Describe("normalize", func() {
 		Expect(err).To(MatchError("invalid host: gitlab.com"))
 	})
 
+	DescribeTable("gists",
+		func(input, expected string) {
+			result, err := Parse(input)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.APIPath()).To(Equal(expected))
+		},
+		Entry(nil, "https://gist.github.com/user/abc123", "gists/abc123"),
+		Entry(nil, "gist.github.com/user/abc123/file.txt", "gists/abc123"),
+		Entry(nil, "gist.github.com/user/abc123/revisions/def456", "gists/abc123/def456"),
+		Entry(nil, "gist.github.com/user/abc123/revisions/def456/file.txt", "gists/abc123/def456"),
+		Entry(nil, "gists/abc123", "gists/abc123"),
+		Entry(nil, "https://api.github.com/gists/abc123/def456", "gists/abc123/def456"),
+	)
+
+	DescribeTable("enterprise hosts",
+		func(input, expected string) {
+			result, err := parse(input, []Host{Enterprise("ghe.example.com")})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.APIPath()).To(Equal(expected))
+		},
+		Entry(nil, "https://ghe.example.com/api/v3/repos/owner/repo", "repos/owner/repo"),
+		Entry(nil, "ghe.example.com/api/v3/users/test-user", "users/test-user"),
+		Entry(nil, "https://ghe.example.com/owner/repo/blob/main/file.txt", "repos/owner/repo/contents/file.txt?ref=main"),
+		Entry(nil, "ghe.example.com/owner/repo/tree/main", "repos/owner/repo/branches/main"),
+		Entry(nil, "https://ghe.example.com/raw/owner/repo/main/file.txt", "repos/owner/repo/contents/file.txt?ref=main"),
+		Entry(nil, "https://github.com/owner/repo", "repos/owner/repo"),
+	)
+
+	It("should not match hosts that share a prefix with a registered host", func() {
+		_, err := parse("https://ghe.example.com.evil/owner/repo", []Host{Enterprise("ghe.example.com")})
+		Expect(err).To(MatchError("invalid host: ghe.example.com.evil"))
+	})
+
+	It("should reject unregistered enterprise hosts", func() {
+		_, err := Parse("https://ghe.example.com/api/v3/repos/owner/repo")
+		Expect(err).To(MatchError("invalid host: ghe.example.com"))
+	})
+
 	It("should return ErrNotExist for invalid URLs", func() {
 		_, err := Parse("%%invalid")
 		Expect(err).To(HaveOccurred())
@@ -0,0 +1,34 @@
+package ghfs
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/go-github/v84/github"
+	"github.com/unstoppablemango/ihfs/internal/httprange"
+)
+
+// newRanged returns a reader of url at arbitrary offsets with HTTP Range requests,
+// made by c with the given Accept header.
+func newRanged(ctx context.Context, c *github.Client, url, accept string, size int64) *httprange.Reader {
+	return httprange.New(func(rng string) (*http.Response, error) {
+		req, err := c.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Range", rng)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+
+		resp, err := c.BareDo(ctx, req)
+		if resp != nil && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			_ = resp.Body.Close()
+			return nil, httprange.ErrNotSatisfiable
+		}
+		if err != nil {
+			return nil, err
+		}
+		return resp.Response, nil
+	}, size)
+}
@@ -0,0 +1,95 @@
+package ghfs
+
+import (
+	"context"
+	"io/fs"
+	"net/url"
+
+	"github.com/google/go-github/v84/github"
+	"github.com/unstoppablemango/ihfs"
+)
+
+// assetMediaType requests the binary content of a release asset instead of its metadata.
+const assetMediaType = "application/octet-stream"
+
+// openReleases opens a path in the browsable release tree: "releases" is a directory of tags,
+// "releases/{tag}" is a directory of the assets of that release, and "releases/{tag}/{asset}"
+// is the content of the asset.
+func (f *Fs) openReleases(ctx context.Context, name string, p Path) (*File, error) {
+	if p.tag == "" {
+		entries, err := f.listReleases(ctx, p.owner, p.repo)
+		if err != nil {
+			return nil, openErr(name, notExist(err))
+		}
+		info := &FileInfo{name: "releases", isDir: true}
+		return &File{name: name, isDir: true, entries: entries, info: info}, nil
+	}
+
+	rel, err := release(ctx, f.client, p)
+	if err != nil {
+		return nil, openErr(name, notExist(err))
+	}
+	if p.asset == "" {
+		entries := make([]fs.DirEntry, 0, len(rel.Assets))
+		for _, a := range rel.Assets {
+			entries = append(entries, fs.FileInfoToDirEntry(assetInfo(a)))
+		}
+		return &File{name: name, isDir: true, entries: entries, info: releaseInfo(rel)}, nil
+	}
+
+	for _, a := range rel.Assets {
+		if a.GetName() != p.asset {
+			continue
+		}
+		resp, err := get(ctx, f.client, assetPath(p.owner, p.repo, a.GetID()), assetMediaType)
+		if err != nil {
+			return nil, openErr(name, notExist(err))
+		}
+		info := assetInfo(a)
+		return &File{
+			name: name,
+			rc:   resp.Body,
+			size: info.size,
+			info: info,
+			ra:   newRanged(ctx, f.client, assetPath(p.owner, p.repo, a.GetID()), assetMediaType, info.size),
+		}, nil
+	}
+	return nil, openErr(name, ihfs.ErrNotExist)
+}
+
+// listReleases returns a directory entry for each release of owner/repo, following pagination.
+func (f *Fs) listReleases(ctx context.Context, owner, repo string) ([]fs.DirEntry, error) {
+	var (
+		entries []fs.DirEntry
+		opts    = &github.ListOptions{PerPage: 100}
+	)
+	for {
+		releases, resp, err := f.client.Repositories.ListReleases(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range releases {
+			entries = append(entries, fs.FileInfoToDirEntry(releaseInfo(rel)))
+		}
+		if resp.NextPage == 0 {
+			return entries, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// releaseInfo describes a release as a directory named by its path escaped tag.
+// Its ModTime is the time the release was published, or created if it is a draft.
+func releaseInfo(rel *github.RepositoryRelease) *FileInfo {
+	modTime := rel.GetPublishedAt().Time
+	if modTime.IsZero() {
+		modTime = rel.GetCreatedAt().Time
+	}
+
+	return &FileInfo{
+		name:    url.PathEscape(rel.GetTagName()),
+		isDir:   true,
+		modTime: modTime,
+		sys:     rel,
+	}
+}
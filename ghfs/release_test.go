@@ -0,0 +1,300 @@
+package ghfs_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/google/go-github/v84/github"
+	"github.com/unstoppablemango/ihfs/ghfs"
+)
+
+// releasesHandler serves releases of owner/repo, one release per page,
+// with one asset named after the release for each of the given platforms.
+func releasesHandler(tags []string, platforms ...string) http.Handler {
+	var (
+		releases []*github.RepositoryRelease
+		assets   = map[string]*github.ReleaseAsset{}
+	)
+	for i, tag := range tags {
+		rel := &github.RepositoryRelease{
+			TagName:     github.Ptr(tag),
+			PublishedAt: &github.Timestamp{Time: time.Date(2024, 1, i+1, 0, 0, 0, 0, time.UTC)},
+		}
+		for _, platform := range platforms {
+			id := int64(len(assets) + 1)
+			a := &github.ReleaseAsset{
+				ID:          github.Ptr(id),
+				Name:        github.Ptr(fmt.Sprintf("tool_%v.tar.gz", platform)),
+				Size:        github.Ptr(len(tag + platform)),
+				ContentType: github.Ptr("application/gzip"),
+			}
+			assets[fmt.Sprint(id)] = a
+			rel.Assets = append(rel.Assets, a)
+		}
+		releases = append(releases, rel)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := strings.TrimPrefix(r.URL.EscapedPath(), "/repos/owner/repo/releases")
+		switch {
+		case p == "":
+			page := 1
+			_, _ = fmt.Sscan(r.URL.Query().Get("page"), &page)
+			if page < len(releases) {
+				w.Header().Set("Link", fmt.Sprintf(`<%v?page=%v>; rel="next"`, r.URL.Path, page+1))
+			}
+			_ = json.NewEncoder(w).Encode(releases[page-1 : page])
+		case p == "/latest":
+			_ = json.NewEncoder(w).Encode(releases[len(releases)-1])
+		case strings.HasPrefix(p, "/tags/"):
+			tag, _ := url.PathUnescape(strings.TrimPrefix(p, "/tags/"))
+			for _, rel := range releases {
+				if rel.GetTagName() == tag {
+					_ = json.NewEncoder(w).Encode(rel)
+					return
+				}
+			}
+			http.NotFound(w, r)
+		case strings.HasPrefix(p, "/assets/"):
+			a, ok := assets[strings.TrimPrefix(p, "/assets/")]
+			if !ok {
+				http.NotFound(w, r)
+			} else if r.Header.Get("Accept") == "application/octet-stream" {
+				_, _ = w.Write([]byte("binary " + a.GetName()))
+			} else {
+				_ = json.NewEncoder(w).Encode(a)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+var _ = Describe("Releases", func() {
+	var fsys *ghfs.Fs
+
+	BeforeEach(func() {
+		fsys = ghfs.New(ghfs.WithClient(testClient(releasesHandler(
+			[]string{"v1.0.0", "v1.1.0", "v2.0.0", "ghfs/v0.1.0"},
+			"linux_amd64", "darwin_arm64",
+		))))
+	})
+
+	It("should list release tags", func() {
+		entries, err := fs.ReadDir(fsys, "github.com/owner/repo/releases")
+
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, e := range entries {
+			Expect(e.IsDir()).To(BeTrue())
+			names = append(names, e.Name())
+		}
+		Expect(names).To(Equal([]string{"ghfs%2Fv0.1.0", "v1.0.0", "v1.1.0", "v2.0.0"}))
+	})
+
+	It("should list the assets of a release", func() {
+		entries, err := fs.ReadDir(fsys, "github.com/owner/repo/releases/v1.0.0")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[1].Name()).To(Equal("tool_linux_amd64.tar.gz"))
+		Expect(entries[1].IsDir()).To(BeFalse())
+		info, err := entries[1].Info()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Size()).To(Equal(int64(len("v1.0.0linux_amd64"))))
+	})
+
+	It("should list the assets of releases with slashes in their tag", func() {
+		entries, err := fs.ReadDir(fsys, "github.com/owner/repo/releases/ghfs%2Fv0.1.0")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(2))
+	})
+
+	It("should stat releases", func() {
+		info, err := fs.Stat(fsys, "github.com/owner/repo/releases/v1.1.0")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.IsDir()).To(BeTrue())
+		Expect(info.Name()).To(Equal("v1.1.0"))
+		Expect(info.ModTime()).To(Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)))
+	})
+
+	It("should stat assets", func() {
+		info, err := fs.Stat(fsys, "github.com/owner/repo/releases/v2.0.0/tool_darwin_arm64.tar.gz")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Size()).To(Equal(int64(len("v2.0.0darwin_arm64"))))
+		Expect(info.Sys().(*github.ReleaseAsset).GetContentType()).To(Equal("application/gzip"))
+	})
+
+	It("should read asset content", func() {
+		data, err := fs.ReadFile(fsys, "github.com/owner/repo/releases/v2.0.0/tool_linux_amd64.tar.gz")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("binary tool_linux_amd64.tar.gz"))
+	})
+
+	It("should open assets of the latest release", func() {
+		data, err := fs.ReadFile(fsys, "github.com/owner/repo/releases/latest/download/tool_linux_amd64.tar.gz")
+
+		Expect(err).NotTo(HaveOccurred())
+		var asset github.ReleaseAsset
+		Expect(json.Unmarshal(data, &asset)).To(Succeed())
+		Expect(asset.GetName()).To(Equal("tool_linux_amd64.tar.gz"))
+		Expect(asset.GetSize()).To(Equal(len("ghfs/v0.1.0linux_amd64")))
+	})
+
+	It("should return ErrNotExist for missing assets", func() {
+		_, err := fsys.Open("github.com/owner/repo/releases/v2.0.0/missing.tar.gz")
+
+		Expect(err).To(MatchError(fs.ErrNotExist))
+	})
+
+	It("should glob assets", func() {
+		repo, err := fs.Sub(fsys, "github.com/owner/repo")
+		Expect(err).NotTo(HaveOccurred())
+
+		matches, err := fs.Glob(repo, "releases/v1.*/tool_linux_amd64.tar.gz")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(matches).To(Equal([]string{
+			"releases/v1.0.0/tool_linux_amd64.tar.gz",
+			"releases/v1.1.0/tool_linux_amd64.tar.gz",
+		}))
+	})
+})
+
+var _ = Describe("Release asset ranges", func() {
+	var (
+		archive []byte
+		ranges  []string
+		served  int64 // bytes served in response to Range requests
+		fsys    *ghfs.Fs
+	)
+
+	BeforeEach(func() {
+		buf := &bytes.Buffer{}
+		zw := zip.NewWriter(buf)
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: "large.bin", Method: zip.Store})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = w.Write(bytes.Repeat([]byte{0xab}, 1<<20))
+		Expect(err).NotTo(HaveOccurred())
+		w, err = zw.Create("README.md")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = w.Write([]byte("# tool"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(zw.Close()).To(Succeed())
+		archive, ranges, served = buf.Bytes(), nil, 0
+
+		fsys = ghfs.New(ghfs.WithClient(testClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/repos/owner/repo/releases/tags/v1.0.0":
+				_ = json.NewEncoder(w).Encode(&github.RepositoryRelease{
+					TagName: github.Ptr("v1.0.0"),
+					Assets: []*github.ReleaseAsset{{
+						ID:   github.Ptr(int64(1)),
+						Name: github.Ptr("tool.zip"),
+						Size: github.Ptr(len(archive)),
+					}},
+				})
+			case "/repos/owner/repo/releases/assets/1":
+				http.Redirect(w, r, "/download/tool.zip", http.StatusFound)
+			case "/download/tool.zip":
+				ranges = append(ranges, r.Header.Get("Range"))
+				cw := &countingWriter{ResponseWriter: w}
+				http.ServeContent(cw, r, "tool.zip", time.Time{}, bytes.NewReader(archive))
+				if r.Header.Get("Range") != "" {
+					served += cw.n
+				}
+			default:
+				http.NotFound(w, r)
+			}
+		}))))
+	})
+
+	It("should read zip assets without downloading them", func() {
+		f, err := fsys.Open("github.com/owner/repo/releases/v1.0.0/tool.zip")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(f.Close)
+		info, err := f.Stat()
+		Expect(err).NotTo(HaveOccurred())
+
+		zr, err := zip.NewReader(f.(io.ReaderAt), info.Size())
+		Expect(err).NotTo(HaveOccurred())
+		data, err := fs.ReadFile(zr, "README.md")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("# tool"))
+		Expect(ranges[1:]).To(HaveEach(HavePrefix("bytes=")))
+		Expect(served).To(BeNumerically("<", len(archive)/2))
+	})
+
+	It("should read from the offset after seeking", func() {
+		f, err := fsys.Open("github.com/owner/repo/releases/v1.0.0/tool.zip")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(f.Close)
+		seeker := f.(io.ReadSeeker)
+
+		off, err := seeker.Seek(-22, io.SeekEnd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(off).To(Equal(int64(len(archive) - 22)))
+		data, err := io.ReadAll(seeker)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(Equal(archive[len(archive)-22:]))
+		Expect(ranges).To(ConsistOf("", fmt.Sprintf("bytes=%d-", len(archive)-22)))
+	})
+
+	It("should read sequentially after seeking with a single request", func() {
+		f, err := fsys.Open("github.com/owner/repo/releases/v1.0.0/tool.zip")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(f.Close)
+
+		_, err = f.(io.Seeker).Seek(1, io.SeekStart)
+		Expect(err).NotTo(HaveOccurred())
+		data, err := io.ReadAll(io.LimitReader(f, 256<<10))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(Equal(archive[1 : 1+256<<10]))
+		Expect(ranges).To(ConsistOf("", "bytes=1-"))
+	})
+
+	It("should continue the initial response when reading in order", func() {
+		f, err := fsys.Open("github.com/owner/repo/releases/v1.0.0/tool.zip")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(f.Close)
+
+		head := make([]byte, 4)
+		_, err = io.ReadFull(f, head)
+		Expect(err).NotTo(HaveOccurred())
+		off, err := f.(io.Seeker).Seek(0, io.SeekCurrent)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(off).To(Equal(int64(4)))
+		Expect(head).To(Equal([]byte("PK\x03\x04")))
+		Expect(ranges).To(Equal([]string{""}))
+	})
+})
+
+type countingWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,72 @@
+package ghfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v84/github"
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/op"
+)
+
+// RepoFS is a read-only [fs.FS] rooted at a ref of a GitHub repository.
+// Names are ordinary slash-separated paths relative to the repository root,
+// so RepoFS works with [fs.Sub], [fs.Glob], [fs.WalkDir] and [ihfs.Copy].
+type RepoFS struct {
+	fs    *Fs
+	owner string
+	repo  string
+	ref   string
+}
+
+// Repo returns a [RepoFS] for the tree of owner/repo at ref. An empty ref uses the default branch.
+// Options configure the underlying [Fs], see [New].
+func Repo(owner, repo, ref string, options ...Option) *RepoFS {
+	return &RepoFS{
+		fs:    New(options...),
+		owner: owner,
+		repo:  repo,
+		ref:   ref,
+	}
+}
+
+// Open implements [fs.FS]. Files are opened with their raw content and
+// directories are listed through the contents API.
+func (r *RepoFS) Open(name string) (ihfs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, openErr(name, ihfs.ErrInvalid)
+	}
+
+	ctx := r.fs.context(op.Open{Name: name})
+	f, err := openContent(ctx, r.fs.client, r.contentPath(name), true)
+	if err != nil {
+		return nil, openErr(name, notExist(err))
+	}
+
+	f.name = name
+	return f, nil
+}
+
+func (r *RepoFS) contentPath(name string) string {
+	var parts []string
+	if name != "." {
+		parts = strings.Split(name, "/")
+	}
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return contentPath(r.owner, r.repo, url.QueryEscape(r.ref), parts)
+}
+
+// notExist maps API 404 responses to [fs.ErrNotExist].
+func notExist(err error) error {
+	var resp *github.ErrorResponse
+	if errors.As(err, &resp) && resp.Response != nil && resp.Response.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %w", fs.ErrNotExist, err)
+	}
+	return err
+}
@@ -0,0 +1,176 @@
+package ghfs_test
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strings"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/google/go-github/v84/github"
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/ghfs"
+	"github.com/unstoppablemango/ihfs/memfs"
+)
+
+// testClient returns a GitHub client for an httptest server running h.
+func testClient(h http.Handler) *github.Client {
+	GinkgoHelper()
+	s := httptest.NewServer(h)
+	DeferCleanup(s.Close)
+
+	c := github.NewClient(s.Client())
+	u, err := url.Parse(s.URL + "/")
+	Expect(err).NotTo(HaveOccurred())
+	c.BaseURL = u
+	return c
+}
+
+// contentsHandler serves fsys from the contents API of owner/repo, with raw file content
+// when it is requested.
+func contentsHandler(fsys fs.FS) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := strings.CutPrefix(r.URL.Path, "/repos/owner/repo/contents/")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if name == "" {
+			name = "."
+		}
+
+		info, err := fs.Stat(fsys, name)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "Not Found"}`))
+			return
+		}
+		if !info.IsDir() {
+			data, _ := fs.ReadFile(fsys, name)
+			if r.Header.Get("Accept") == "application/vnd.github.raw+json" {
+				w.Header().Set("Content-Type", "application/vnd.github.raw")
+				_, _ = w.Write(data)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(github.RepositoryContent{
+				Name: github.Ptr(info.Name()),
+				Type: github.Ptr("file"),
+				Size: github.Ptr(len(data)),
+				SHA:  github.Ptr(fmt.Sprintf("%x", sha1.Sum(data))),
+			})
+			return
+		}
+
+		entries, _ := fs.ReadDir(fsys, name)
+		contents := []*github.RepositoryContent{}
+		for _, e := range entries {
+			c := &github.RepositoryContent{
+				Name: github.Ptr(e.Name()),
+				Path: github.Ptr(path.Join(name, e.Name())),
+				Type: github.Ptr("file"),
+			}
+			if e.IsDir() {
+				c.Type = github.Ptr("dir")
+			} else {
+				info, _ := e.Info()
+				c.Size = github.Ptr(int(info.Size()))
+			}
+			contents = append(contents, c)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(contents)
+	})
+}
+
+var _ = Describe("RepoFS", func() {
+	var (
+		repo  fstest.MapFS
+		fsys  *ghfs.RepoFS
+		paths []string
+	)
+
+	BeforeEach(func() {
+		repo = fstest.MapFS{
+			"README.md":         {Data: []byte("# repo")},
+			"cmd/main.go":       {Data: []byte("package main")},
+			"internal/a/a.go":   {Data: []byte("package a")},
+			"internal/b/b.go":   {Data: []byte("package b")},
+			"testdata/list.txt": {Data: []byte(`["not", "a", "dir"]`)},
+		}
+		paths = nil
+
+		fsys = ghfs.Repo("owner", "repo", "main", ghfs.WithClient(testClient(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				paths = append(paths, r.URL.RequestURI())
+				contentsHandler(repo).ServeHTTP(w, r)
+			}),
+		)))
+	})
+
+	It("should read files with relative paths", func() {
+		data, err := fs.ReadFile(fsys, "cmd/main.go")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("package main"))
+		Expect(paths).To(Equal([]string{"/repos/owner/repo/contents/cmd/main.go?ref=main"}))
+	})
+
+	It("should read raw files that look like a listing", func() {
+		data, err := fs.ReadFile(fsys, "testdata/list.txt")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(`["not", "a", "dir"]`))
+	})
+
+	It("should list the repository root", func() {
+		entries, err := fs.ReadDir(fsys, ".")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(4))
+	})
+
+	It("should return ErrNotExist for missing files", func() {
+		_, err := fsys.Open("missing.txt")
+
+		Expect(err).To(MatchError(fs.ErrNotExist))
+	})
+
+	It("should reject invalid paths", func() {
+		_, err := fsys.Open("/cmd/main.go")
+
+		Expect(err).To(MatchError(ihfs.ErrInvalid))
+	})
+
+	It("should work with fs.Sub and fs.Glob", func() {
+		sub, err := fs.Sub(fsys, "internal")
+		Expect(err).NotTo(HaveOccurred())
+
+		matches, err := fs.Glob(sub, "*/*.go")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(matches).To(Equal([]string{"a/a.go", "b/b.go"}))
+	})
+
+	It("should copy into memfs", func() {
+		dest := memfs.New()
+
+		Expect(ihfs.Copy(dest, ".", fsys)).To(Succeed())
+
+		data, err := fs.ReadFile(dest, "internal/b/b.go")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("package b"))
+	})
+
+	It("should pass fstest.TestFS", func() {
+		Expect(fstest.TestFS(fsys, "README.md", "cmd/main.go", "internal/a/a.go")).To(Succeed())
+	})
+})
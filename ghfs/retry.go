@@ -0,0 +1,184 @@
+package ghfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// RateLimitError is returned, wrapped in an [ihfs.PathError], when GitHub rejects a request because
+// a rate limit was exceeded and the request was not retried. Use [errors.As] to inspect it.
+type RateLimitError struct {
+	// StatusCode is the status of the rejected response, 403 or 429.
+	StatusCode int
+
+	// Limit and Remaining are the values of the X-RateLimit-Limit and X-RateLimit-Remaining headers.
+	Limit     int
+	Remaining int
+
+	// Reset is the time the primary rate limit resets, from the X-RateLimit-Reset header.
+	Reset time.Time
+
+	// RetryAfter is how long GitHub asked to wait before retrying after a secondary rate limit.
+	RetryAfter time.Duration
+
+	secondary bool
+}
+
+func (e *RateLimitError) Error() string {
+	if e.secondary {
+		return fmt.Sprintf("github: secondary rate limit exceeded, retry after %v", e.RetryAfter)
+	}
+	return fmt.Sprintf("github: rate limit of %v exceeded until %v", e.Limit, e.Reset)
+}
+
+// retryTransport retries requests that fail with a secondary rate limit, and GET and HEAD requests
+// that fail with a 5xx, waiting backoff and doubling it after each attempt, or as long as the
+// Retry-After header asks if that is longer. Other requests are not retried on a 5xx, since the
+// server may have applied them before failing.
+// Requests that exceed the primary rate limit fail with a [RateLimitError], or wait until the limit
+// resets if wait is set.
+type retryTransport struct {
+	base    http.RoundTripper
+	retries int
+	backoff time.Duration
+	wait    bool
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Requests with a body can only be retried if it can be read again.
+	rewindable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	r := req
+	for attempt := 0; ; {
+		resp, err := t.base.RoundTrip(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var delay time.Duration
+		if limit := rateLimit(resp); limit != nil {
+			switch {
+			case !limit.secondary && t.wait && rewindable:
+				delay = max(time.Until(limit.Reset), t.backoff)
+			case limit.secondary && attempt < t.retries && rewindable:
+				delay = max(limit.RetryAfter, t.backoff<<attempt)
+				attempt++
+			default:
+				discard(resp)
+				return nil, limit
+			}
+		} else if resp.StatusCode >= 500 && attempt < t.retries && idempotent(req) {
+			delay = t.backoff << attempt
+			attempt++
+		} else {
+			return resp, nil
+		}
+
+		discard(resp)
+		if err := sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+		if r, err = rewind(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// rateLimit returns a [RateLimitError] describing resp if it was rejected by a rate limit.
+// Responses with a Retry-After header, and 429 responses that have not used up the primary limit,
+// are secondary rate limits.
+func rateLimit(resp *http.Response) *RateLimitError {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+
+	h := resp.Header
+	err := &RateLimitError{
+		StatusCode: resp.StatusCode,
+		Limit:      headerInt(h, "X-RateLimit-Limit"),
+		Remaining:  headerInt(h, "X-RateLimit-Remaining"),
+	}
+	if reset := headerInt(h, "X-RateLimit-Reset"); reset > 0 {
+		err.Reset = time.Unix(int64(reset), 0)
+	}
+
+	switch retryAfter := h.Get("Retry-After"); {
+	case retryAfter != "":
+		err.secondary = true
+		if s, perr := strconv.Atoi(retryAfter); perr == nil {
+			err.RetryAfter = time.Duration(s) * time.Second
+		} else if t, perr := http.ParseTime(retryAfter); perr == nil {
+			err.RetryAfter = time.Until(t)
+		}
+	case h.Get("X-RateLimit-Remaining") == "0":
+	case resp.StatusCode == http.StatusTooManyRequests:
+		err.secondary = true
+	default:
+		return nil
+	}
+	return err
+}
+
+func headerInt(h http.Header, key string) int {
+	v, _ := strconv.Atoi(h.Get(key))
+	return v
+}
+
+// idempotent reports whether req can be sent again after a server error without repeating its effect.
+func idempotent(req *http.Request) bool {
+	return req.Method == http.MethodGet || req.Method == http.MethodHead
+}
+
+// rewind returns a copy of req with a fresh body to send it again.
+func rewind(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	return r, nil
+}
+
+// discard drains and closes the body of resp so the connection can be reused.
+func discard(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// withRetry returns a copy of c that retries requests through a [retryTransport].
+func withRetry(c *github.Client, t retryTransport) *github.Client {
+	hc := c.Client()
+	t.base = hc.Transport
+	if t.base == nil {
+		t.base = http.DefaultTransport
+	}
+	hc.Transport = &t
+
+	retrying := github.NewClient(hc)
+	retrying.BaseURL, retrying.UploadURL, retrying.UserAgent = c.BaseURL, c.UploadURL, c.UserAgent
+	return retrying
+}
@@ -0,0 +1,198 @@
+package ghfs_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/ghfs"
+)
+
+var _ = Describe("Retries", func() {
+	const name = "github.com/owner/repo/blob/main/README.md"
+
+	var (
+		requests  int
+		responses []func(http.ResponseWriter)
+	)
+
+	BeforeEach(func() {
+		requests, responses = 0, nil
+	})
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if len(responses) == 0 {
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("# repo"))
+			return
+		}
+		respond := responses[0]
+		responses = responses[1:]
+		respond(w)
+	})
+
+	status := func(code int, headers ...string) func(http.ResponseWriter) {
+		return func(w http.ResponseWriter) {
+			for i := 0; i+1 < len(headers); i += 2 {
+				w.Header().Set(headers[i], headers[i+1])
+			}
+			w.WriteHeader(code)
+		}
+	}
+
+	It("should retry server errors", func() {
+		responses = append(responses, status(http.StatusBadGateway), status(http.StatusServiceUnavailable))
+		fsys := ghfs.New(ghfs.WithClient(testClient(handler)), ghfs.WithRetries(3, time.Millisecond))
+
+		data, err := fs.ReadFile(fsys, name)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("# repo"))
+		Expect(requests).To(Equal(3))
+	})
+
+	It("should give up after the configured number of retries", func() {
+		for range 3 {
+			responses = append(responses, status(http.StatusInternalServerError))
+		}
+		fsys := ghfs.New(ghfs.WithClient(testClient(handler)), ghfs.WithRetries(2, time.Millisecond))
+
+		_, err := fs.ReadFile(fsys, name)
+
+		Expect(err).To(HaveOccurred())
+		Expect(requests).To(Equal(3))
+	})
+
+	It("should retry secondary rate limits after Retry-After", func() {
+		responses = append(responses, status(http.StatusForbidden, "Retry-After", "0"))
+		fsys := ghfs.New(ghfs.WithClient(testClient(handler)), ghfs.WithRetries(1, time.Millisecond))
+
+		_, err := fs.ReadFile(fsys, name)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(Equal(2))
+	})
+
+	It("should return a RateLimitError when secondary rate limit retries are exhausted", func() {
+		for range 2 {
+			responses = append(responses, status(http.StatusTooManyRequests, "Retry-After", "0"))
+		}
+		fsys := ghfs.New(ghfs.WithClient(testClient(handler)), ghfs.WithRetries(1, time.Millisecond))
+
+		_, err := fsys.Open(name)
+
+		var limit *ghfs.RateLimitError
+		Expect(errors.As(err, &limit)).To(BeTrue())
+		Expect(limit.StatusCode).To(Equal(http.StatusTooManyRequests))
+		Expect(requests).To(Equal(2))
+	})
+
+	It("should fail with a RateLimitError when the primary rate limit is exceeded", func() {
+		reset := time.Now().Add(time.Hour).Truncate(time.Second)
+		responses = append(responses, status(http.StatusForbidden,
+			"X-RateLimit-Limit", "5000",
+			"X-RateLimit-Remaining", "0",
+			"X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10),
+		))
+		fsys := ghfs.New(ghfs.WithClient(testClient(handler)), ghfs.WithRetries(3, time.Millisecond))
+
+		_, err := fsys.Open(name)
+
+		var pathErr *ihfs.PathError
+		Expect(errors.As(err, &pathErr)).To(BeTrue())
+		Expect(pathErr.Path).To(Equal(name))
+		var limit *ghfs.RateLimitError
+		Expect(errors.As(err, &limit)).To(BeTrue())
+		Expect(limit.Limit).To(Equal(5000))
+		Expect(limit.Remaining).To(Equal(0))
+		Expect(limit.Reset).To(Equal(reset))
+		Expect(requests).To(Equal(1))
+	})
+
+	It("should wait for the primary rate limit to reset with WithRateLimitWait", func() {
+		responses = append(responses, status(http.StatusForbidden,
+			"X-RateLimit-Remaining", "0",
+			"X-RateLimit-Reset", strconv.FormatInt(time.Now().Unix(), 10),
+		))
+		fsys := ghfs.New(
+			ghfs.WithClient(testClient(handler)),
+			ghfs.WithRetries(0, time.Millisecond),
+			ghfs.WithRateLimitWait(),
+		)
+
+		data, err := fs.ReadFile(fsys, name)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("# repo"))
+		Expect(requests).To(Equal(2))
+	})
+
+	It("should not treat other forbidden responses as rate limits", func() {
+		responses = append(responses, status(http.StatusForbidden))
+		fsys := ghfs.New(ghfs.WithClient(testClient(handler)), ghfs.WithRetries(3, time.Millisecond))
+
+		_, err := fsys.Open(name)
+
+		var limit *ghfs.RateLimitError
+		Expect(errors.As(err, &limit)).To(BeFalse())
+		Expect(requests).To(Equal(1))
+	})
+
+	It("should send the request body again when retrying writes", func() {
+		var bodies []string
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				http.NotFound(w, r)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			bodies = append(bodies, string(body))
+			if len(bodies) == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			_, _ = w.Write([]byte(`{}`))
+		})
+		fsys := ghfs.Repo("owner", "repo", "main",
+			ghfs.WithClient(testClient(handler)),
+			ghfs.WithRetries(1, time.Millisecond),
+		)
+
+		err := fsys.WriteFile("file.txt", []byte("content"), 0o644)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bodies).To(HaveLen(2))
+		Expect(bodies[1]).To(Equal(bodies[0]))
+		Expect(bodies[0]).NotTo(BeEmpty())
+	})
+
+	It("should not retry writes that fail with a server error", func() {
+		var puts int
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				http.NotFound(w, r)
+				return
+			}
+			puts++
+			w.WriteHeader(http.StatusBadGateway)
+		})
+		fsys := ghfs.Repo("owner", "repo", "main",
+			ghfs.WithClient(testClient(handler)),
+			ghfs.WithRetries(3, time.Millisecond),
+		)
+
+		err := fsys.WriteFile("file.txt", []byte("content"), 0o644)
+
+		Expect(err).To(HaveOccurred())
+		Expect(puts).To(Equal(1))
+	})
+})
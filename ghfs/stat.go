@@ -0,0 +1,157 @@
+package ghfs
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/v84/github"
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/op"
+)
+
+// Stat implements [fs.StatFS]. Release assets and repository contents are described from their
+// API metadata without downloading them; other paths are opened and described from the response.
+func (f *Fs) Stat(name string) (ihfs.FileInfo, error) {
+	if name == "." {
+		return &FileInfo{name: ".", isDir: true}, nil
+	}
+	if !strings.Contains(name, "://") && !fs.ValidPath(name) {
+		return nil, statErr(name, ihfs.ErrInvalid)
+	}
+	p, err := parse(name, f.hosts)
+	if err != nil {
+		return nil, statErr(name, err)
+	}
+	f = f.on(p)
+
+	ctx := f.context(op.Stat{Name: name})
+	switch {
+	case p.asset != "" || p.assetID != 0:
+		info, err := f.statAsset(ctx, p)
+		if err != nil {
+			return nil, statErr(name, err)
+		}
+		return info, nil
+	case len(p.content) > 0:
+		info, err := f.statContent(ctx, p.owner, p.repo, p.branch, path.Join(p.content...))
+		if err != nil {
+			return nil, statErr(name, err)
+		}
+		return info, nil
+	}
+
+	file, err := f.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	return file.Stat()
+}
+
+// Stat implements [fs.StatFS] using the metadata from the contents API.
+func (r *RepoFS) Stat(name string) (ihfs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, statErr(name, ihfs.ErrInvalid)
+	}
+
+	ctx := r.fs.context(op.Stat{Name: name})
+	p := name
+	if name == "." {
+		p = ""
+	}
+	info, err := r.fs.statContent(ctx, r.owner, r.repo, r.ref, p)
+	if err != nil {
+		return nil, statErr(name, err)
+	}
+	return info, nil
+}
+
+// Version implements [ihfs.VersionFS] with the blob SHA of a file, which changes with its content.
+func (r *RepoFS) Version(name string) (string, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return "", versionErr(name, ihfs.ErrInvalid)
+	}
+
+	ctx := r.fs.context(op.Stat{Name: name})
+	file, _, _, err := r.fs.client.Repositories.GetContents(ctx, r.owner, r.repo, name,
+		&github.RepositoryContentGetOptions{Ref: r.ref},
+	)
+	if err != nil {
+		return "", versionErr(name, notExist(err))
+	}
+	if file == nil {
+		return "", versionErr(name, ihfs.ErrInvalid)
+	}
+	return file.GetSHA(), nil
+}
+
+func (f *Fs) statAsset(ctx context.Context, p Path) (*FileInfo, error) {
+	id, err := f.assetId(ctx, p)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	asset, _, err := f.client.Repositories.GetReleaseAsset(ctx, p.owner, p.repo, id)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	return assetInfo(asset), nil
+}
+
+// statContent describes the file or directory at p in owner/repo at ref.
+func (f *Fs) statContent(ctx context.Context, owner, repo, ref, p string) (*FileInfo, error) {
+	file, dir, _, err := f.client.Repositories.GetContents(ctx, owner, repo, p,
+		&github.RepositoryContentGetOptions{Ref: ref},
+	)
+	if err != nil {
+		return nil, notExist(err)
+	}
+
+	var info *FileInfo
+	if file != nil {
+		info = contentInfo(file)
+	} else {
+		info = &FileInfo{name: path.Base(p), isDir: true, sys: dir}
+		if p == "" {
+			info.name = "."
+		}
+	}
+
+	if err := f.setCommitTime(ctx, info, owner, repo, ref, p); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// setCommitTime sets the ModTime of info to the date of the last commit on ref that changed p,
+// if f was created with [WithCommitTimes].
+func (f *Fs) setCommitTime(ctx context.Context, info *FileInfo, owner, repo, ref, p string) error {
+	if !f.commitTimes {
+		return nil
+	}
+	if p == "." {
+		p = ""
+	}
+
+	commits, _, err := f.client.Repositories.ListCommits(ctx, owner, repo, &github.CommitsListOptions{
+		SHA:         ref,
+		Path:        p,
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	if err != nil {
+		return err
+	}
+	if len(commits) > 0 {
+		info.modTime = commits[0].GetCommit().GetCommitter().GetDate().Time
+	}
+	return nil
+}
+
+func statErr(name string, err error) error {
+	return &ihfs.PathError{Op: "stat", Path: name, Err: err}
+}
+
+func versionErr(name string, err error) error {
+	return &ihfs.PathError{Op: "version", Path: name, Err: err}
+}
@@ -0,0 +1,208 @@
+package ghfs_test
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strings"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/google/go-github/v84/github"
+	"github.com/unstoppablemango/go-github-mock/src/mock"
+	"github.com/unstoppablemango/ihfs/ghfs"
+)
+
+var _ = Describe("Stat", func() {
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	It("should describe release assets", func() {
+		c, s := mock.NewMockedHTTPClientAndServer(
+			mock.WithRequestMatch(
+				mock.GetReposReleasesTagsByOwnerByRepoByTag,
+				github.RepositoryRelease{Assets: []*github.ReleaseAsset{{
+					ID:   github.Ptr(int64(1)),
+					Name: github.Ptr("tool.tar.gz"),
+				}}},
+			),
+			mock.WithRequestMatch(
+				mock.GetReposReleasesAssetsByOwnerByRepoByAssetId,
+				github.ReleaseAsset{
+					ID:        github.Ptr(int64(1)),
+					Name:      github.Ptr("tool.tar.gz"),
+					Size:      github.Ptr(1024),
+					UpdatedAt: &github.Timestamp{Time: updated},
+				},
+			),
+		)
+		DeferCleanup(s.Close)
+		fsys := ghfs.New(ghfs.WithHttpClient(c))
+
+		info, err := fs.Stat(fsys, "github.com/owner/repo/releases/download/v1.0.0/tool.tar.gz")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Name()).To(Equal("tool.tar.gz"))
+		Expect(info.Size()).To(Equal(int64(1024)))
+		Expect(info.ModTime()).To(Equal(updated))
+		Expect(info.Mode()).To(Equal(fs.FileMode(0444)))
+		Expect(info.Sys()).To(BeAssignableToTypeOf(&github.ReleaseAsset{}))
+	})
+
+	Describe("contents", func() {
+		var (
+			handler http.Handler
+			commits []string
+		)
+
+		BeforeEach(func() {
+			commits = nil
+			contents := contentsHandler(fstest.MapFS{
+				"README.md":   {Data: []byte("# repo")},
+				"cmd/main.go": {Data: []byte("package main")},
+			})
+			handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/repos/owner/repo/commits":
+					commits = append(commits, r.URL.Query().Get("path"))
+					_ = json.NewEncoder(w).Encode([]*github.RepositoryCommit{{
+						Commit: &github.Commit{Committer: &github.CommitAuthor{
+							Date: &github.Timestamp{Time: updated},
+						}},
+					}})
+				case "/repos/owner/repo/contents/link":
+					_, _ = w.Write([]byte(`{"name": "link", "type": "symlink", "target": "README.md"}`))
+				case "/repos/owner/repo/contents/vendor":
+					_, _ = w.Write([]byte(`{"name": "vendor", "type": "submodule"}`))
+				default:
+					contents.ServeHTTP(w, r)
+				}
+			})
+		})
+
+		It("should describe files", func() {
+			fsys := ghfs.Repo("owner", "repo", "main", ghfs.WithClient(testClient(handler)))
+
+			info, err := fsys.Stat("cmd/main.go")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Name()).To(Equal("main.go"))
+			Expect(info.Size()).To(Equal(int64(12)))
+			Expect(info.ModTime().IsZero()).To(BeTrue())
+			Expect(info.Sys()).To(BeAssignableToTypeOf(&github.RepositoryContent{}))
+			Expect(commits).To(BeEmpty())
+		})
+
+		It("should describe directories", func() {
+			fsys := ghfs.Repo("owner", "repo", "main", ghfs.WithClient(testClient(handler)))
+
+			info, err := fsys.Stat("cmd")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.IsDir()).To(BeTrue())
+			Expect(info.Name()).To(Equal("cmd"))
+
+			info, err = fsys.Stat(".")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.IsDir()).To(BeTrue())
+			Expect(info.Name()).To(Equal("."))
+		})
+
+		It("should describe symlinks and submodules", func() {
+			fsys := ghfs.Repo("owner", "repo", "main", ghfs.WithClient(testClient(handler)))
+
+			info, err := fsys.Stat("link")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Type()).To(Equal(fs.ModeSymlink))
+
+			info, err = fsys.Stat("vendor")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Type()).To(Equal(fs.ModeIrregular))
+		})
+
+		It("should return ErrNotExist for missing files", func() {
+			fsys := ghfs.Repo("owner", "repo", "main", ghfs.WithClient(testClient(handler)))
+
+			_, err := fsys.Stat("missing")
+
+			Expect(err).To(MatchError(fs.ErrNotExist))
+		})
+
+		It("should use the last commit date with WithCommitTimes", func() {
+			fsys := ghfs.Repo("owner", "repo", "main",
+				ghfs.WithClient(testClient(handler)),
+				ghfs.WithCommitTimes(),
+			)
+
+			info, err := fsys.Stat("cmd/main.go")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.ModTime()).To(Equal(updated))
+			Expect(commits).To(Equal([]string{"cmd/main.go"}))
+		})
+
+		It("should version files by their SHA", func() {
+			fsys := ghfs.Repo("owner", "repo", "main", ghfs.WithClient(testClient(handler)))
+
+			version, err := fsys.Version("cmd/main.go")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(Equal(fmt.Sprintf("%x", sha1.Sum([]byte("package main")))))
+		})
+
+		It("should not version directories", func() {
+			fsys := ghfs.Repo("owner", "repo", "main", ghfs.WithClient(testClient(handler)))
+
+			_, err := fsys.Version("cmd")
+
+			Expect(err).To(MatchError(fs.ErrInvalid))
+		})
+
+		It("should describe content paths of Fs", func() {
+			fsys := ghfs.New(ghfs.WithClient(testClient(handler)))
+
+			info, err := fsys.Stat("github.com/owner/repo/blob/main/README.md")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Size()).To(Equal(int64(6)))
+		})
+	})
+
+	Describe("trees", func() {
+		It("should describe tree entries", func() {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/commits") {
+					_ = json.NewEncoder(w).Encode([]*github.RepositoryCommit{{
+						Commit: &github.Commit{Committer: &github.CommitAuthor{
+							Date: &github.Timestamp{Time: updated},
+						}},
+					}})
+					return
+				}
+				_ = json.NewEncoder(w).Encode(github.Tree{Entries: []*github.TreeEntry{
+					{Path: github.Ptr("run.sh"), Mode: github.Ptr("100755"), Type: github.Ptr("blob"), Size: github.Ptr(10)},
+					{Path: github.Ptr("link"), Mode: github.Ptr("120000"), Type: github.Ptr("blob"), Size: github.Ptr(6)},
+					{Path: github.Ptr("vendor"), Mode: github.Ptr("160000"), Type: github.Ptr("commit")},
+				}})
+			})
+			fsys := ghfs.Tree("owner", "repo", "main", ghfs.WithClient(testClient(handler)), ghfs.WithCommitTimes())
+
+			info, err := fsys.Stat("run.sh")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode()).To(Equal(fs.FileMode(0555)))
+			Expect(info.ModTime()).To(Equal(updated))
+			Expect(info.Sys()).To(BeAssignableToTypeOf(&github.TreeEntry{}))
+
+			info, err = fsys.Stat("link")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Type()).To(Equal(fs.ModeSymlink))
+
+			info, err = fsys.Stat("vendor")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Type()).To(Equal(fs.ModeIrregular))
+		})
+	})
+})
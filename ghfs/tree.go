@@ -0,0 +1,300 @@
+package ghfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/google/go-github/v84/github"
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/op"
+)
+
+// blobMediaType requests the raw content of a blob from the git database API.
+const blobMediaType = "application/vnd.github.raw"
+
+// TreeFS is a read-only [fs.FS] rooted at a ref of a GitHub repository, backed by the git trees API.
+//
+// The whole tree is fetched with a single recursive request the first time it is needed, and
+// Stat and ReadDir are answered from that index. File contents are fetched lazily by blob SHA.
+// When GitHub truncates the recursive response for very large trees, directories are instead
+// fetched one at a time as they are visited. Submodules are listed, but opening one fails
+// with [fs.ErrInvalid], since its content lives in another repository.
+type TreeFS struct {
+	fs    *Fs
+	owner string
+	repo  string
+	ref   string
+
+	mu   sync.Mutex
+	dirs map[string][]*DirEntry
+}
+
+// Tree returns a [TreeFS] for the tree of owner/repo at ref.
+// Options configure the underlying [Fs], see [New].
+func Tree(owner, repo, ref string, options ...Option) *TreeFS {
+	return &TreeFS{
+		fs:    New(options...),
+		owner: owner,
+		repo:  repo,
+		ref:   ref,
+	}
+}
+
+// Open implements [fs.FS].
+func (t *TreeFS) Open(name string) (ihfs.File, error) {
+	ctx := t.fs.context(op.Open{Name: name})
+	if !fs.ValidPath(name) {
+		return nil, openErr(name, ihfs.ErrInvalid)
+	}
+
+	info, err := t.stat(ctx, name)
+	if err != nil {
+		return nil, openErr(name, err)
+	}
+	if info.IsDir() {
+		entries, err := t.readDir(ctx, name)
+		if err != nil {
+			return nil, openErr(name, err)
+		}
+		return &File{name: name, isDir: true, entries: toDirEntries(entries), info: info}, nil
+	}
+
+	e := info.sys.(*github.TreeEntry)
+	if e.GetType() == "commit" {
+		// Submodules point to a commit in another repository and have no content of their own.
+		return nil, openErr(name, ihfs.ErrInvalid)
+	}
+
+	blob := blobPath(t.owner, t.repo, e.GetSHA())
+	resp, err := get(ctx, t.fs.client, blob, blobMediaType)
+	if err != nil {
+		return nil, openErr(name, notExist(err))
+	}
+
+	return &File{
+		name: name,
+		rc:   resp.Body,
+		size: info.size,
+		info: info,
+		ra:   newRanged(ctx, t.fs.client, blob, blobMediaType, info.size),
+	}, nil
+}
+
+// Stat implements [fs.StatFS] without fetching the content of name.
+func (t *TreeFS) Stat(name string) (ihfs.FileInfo, error) {
+	ctx := t.fs.context(op.Stat{Name: name})
+	if !fs.ValidPath(name) {
+		return nil, t.error("stat", name, ihfs.ErrInvalid)
+	}
+
+	info, err := t.stat(ctx, name)
+	if err != nil {
+		return nil, t.error("stat", name, err)
+	}
+	return info, nil
+}
+
+func (t *TreeFS) stat(ctx context.Context, name string) (*FileInfo, error) {
+	var info *FileInfo
+	if name == "." {
+		if err := t.load(ctx); err != nil {
+			return nil, err
+		}
+		info = &FileInfo{name: ".", isDir: true}
+	} else {
+		e, err := t.entry(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		info = e.info()
+	}
+
+	if err := t.fs.setCommitTime(ctx, info, t.owner, t.repo, t.ref, name); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Version implements [ihfs.VersionFS] with the SHA of the blob or tree at name, from the index.
+func (t *TreeFS) Version(name string) (string, error) {
+	ctx := t.fs.context(op.Stat{Name: name})
+	if !fs.ValidPath(name) {
+		return "", t.error("version", name, ihfs.ErrInvalid)
+	}
+
+	e, err := t.entry(ctx, name)
+	if err != nil {
+		return "", t.error("version", name, err)
+	}
+	return e.content.GetSHA(), nil
+}
+
+// ReadDir implements [fs.ReadDirFS].
+func (t *TreeFS) ReadDir(name string) ([]ihfs.DirEntry, error) {
+	ctx := t.fs.context(op.ReadDir{Name: name})
+	if !fs.ValidPath(name) {
+		return nil, t.error("readdir", name, ihfs.ErrInvalid)
+	}
+
+	entries, err := t.readDir(ctx, name)
+	if err != nil {
+		return nil, t.error("readdir", name, err)
+	}
+	return toDirEntries(entries), nil
+}
+
+// load fetches the recursive tree of the ref and indexes its directories, unless it has
+// already been loaded. Failed loads are retried by the next call.
+func (t *TreeFS) load(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.dirs != nil {
+		return nil
+	}
+
+	tree, _, err := t.fs.client.Git.GetTree(ctx, t.owner, t.repo, t.ref, true)
+	if err != nil {
+		return notExist(err)
+	}
+	if tree.GetTruncated() {
+		t.dirs = map[string][]*DirEntry{}
+		return nil
+	}
+
+	dirs := map[string][]*DirEntry{".": nil}
+	for _, e := range tree.Entries {
+		p := e.GetPath()
+		if _, ok := dirs[p]; !ok && e.GetType() == "tree" {
+			dirs[p] = nil
+		}
+		dir := path.Dir(p)
+		dirs[dir] = append(dirs[dir], treeEntry(p, e))
+	}
+	for _, entries := range dirs {
+		sortDirEntries(entries)
+	}
+
+	t.dirs = dirs
+	return nil
+}
+
+// readDir returns the entries of the directory name, fetching it if the index is incomplete.
+// It returns [fs.ErrInvalid] when name is not a directory.
+func (t *TreeFS) readDir(ctx context.Context, name string) ([]*DirEntry, error) {
+	if err := t.load(ctx); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	entries, ok := t.dirs[name]
+	t.mu.Unlock()
+	if ok {
+		return entries, nil
+	}
+
+	sha := t.ref
+	if name != "." {
+		e, err := t.entry(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsDir() {
+			return nil, fs.ErrInvalid
+		}
+		sha = e.content.GetSHA()
+	}
+
+	tree, _, err := t.fs.client.Git.GetTree(ctx, t.owner, t.repo, sha, false)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	entries = make([]*DirEntry, 0, len(tree.Entries))
+	for _, e := range tree.Entries {
+		entries = append(entries, treeEntry(path.Join(name, e.GetPath()), e))
+	}
+	sortDirEntries(entries)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dirs[name] = entries
+	return entries, nil
+}
+
+// entry looks up name in its parent directory.
+func (t *TreeFS) entry(ctx context.Context, name string) (*DirEntry, error) {
+	if name == "." {
+		return nil, fs.ErrInvalid
+	}
+
+	entries, err := t.readDir(ctx, path.Dir(name))
+	if errors.Is(err, fs.ErrInvalid) {
+		return nil, fs.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	base := path.Base(name)
+	i := sort.Search(len(entries), func(i int) bool {
+		return entries[i].Name() >= base
+	})
+	if i < len(entries) && entries[i].Name() == base {
+		return entries[i], nil
+	}
+	return nil, fs.ErrNotExist
+}
+
+func (t *TreeFS) error(op, name string, err error) error {
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// treeEntry converts a git tree entry at p, relative to the repository root, into a [DirEntry].
+func treeEntry(p string, e *github.TreeEntry) *DirEntry {
+	typ := "file"
+	switch {
+	case e.GetType() == "tree":
+		typ = "dir"
+	case e.GetType() == "commit":
+		typ = "submodule"
+	case e.GetMode() == "120000":
+		typ = "symlink"
+	}
+
+	entry := &DirEntry{
+		content: &github.RepositoryContent{
+			Name: github.Ptr(path.Base(p)),
+			Path: github.Ptr(p),
+			Type: github.Ptr(typ),
+			Size: e.Size,
+			SHA:  e.SHA,
+		},
+		sys: e,
+	}
+	if e.GetMode() == "100755" {
+		entry.mode = 0555
+	}
+	return entry
+}
+
+func toDirEntries(entries []*DirEntry) []fs.DirEntry {
+	result := make([]fs.DirEntry, len(entries))
+	for i, e := range entries {
+		result[i] = e
+	}
+	return result
+}
+
+func sortDirEntries(entries []*DirEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+}
+
+func blobPath(owner, repo, sha string) string {
+	return fmt.Sprintf("repos/%v/%v/git/blobs/%v", owner, repo, sha)
+}
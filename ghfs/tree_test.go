@@ -0,0 +1,214 @@
+package ghfs_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/google/go-github/v84/github"
+	"github.com/unstoppablemango/ihfs/ghfs"
+)
+
+// treesHandler serves fsys from the git trees and blobs APIs of owner/repo at ref main.
+// The SHA of each tree and blob is its hex encoded path. Irregular files are served as
+// submodules. When truncated is true, recursive responses only contain the first entry
+// and are marked as truncated.
+func treesHandler(fsys fs.FS, truncated bool) http.Handler {
+	sha := func(p string) string {
+		return hex.EncodeToString([]byte(p))
+	}
+	unsha := func(s string) string {
+		if s == "main" {
+			return "."
+		}
+		p, _ := hex.DecodeString(s)
+		return string(p)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s, ok := strings.CutPrefix(r.URL.Path, "/repos/owner/repo/git/blobs/"); ok {
+			data, err := fs.ReadFile(fsys, unsha(s))
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write(data)
+			return
+		}
+
+		s, ok := strings.CutPrefix(r.URL.Path, "/repos/owner/repo/git/trees/")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		root := unsha(s)
+		recursive := r.URL.Query().Get("recursive") != ""
+
+		tree := github.Tree{SHA: github.Ptr(s)}
+		err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || p == root {
+				return err
+			}
+			rel := strings.TrimPrefix(p, root+"/")
+			if root == "." {
+				rel = p
+			}
+			e := &github.TreeEntry{
+				Path: github.Ptr(rel),
+				SHA:  github.Ptr(sha(p)),
+				Mode: github.Ptr("100644"),
+				Type: github.Ptr("blob"),
+			}
+			if d.IsDir() {
+				e.Mode, e.Type = github.Ptr("040000"), github.Ptr("tree")
+			} else if d.Type()&fs.ModeIrregular != 0 {
+				e.Mode, e.Type = github.Ptr("160000"), github.Ptr("commit")
+			} else {
+				info, _ := d.Info()
+				e.Size = github.Ptr(int(info.Size()))
+			}
+			tree.Entries = append(tree.Entries, e)
+
+			if d.IsDir() && !recursive {
+				return fs.SkipDir
+			}
+			return nil
+		})
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if recursive && truncated {
+			tree.Entries = tree.Entries[:1]
+			tree.Truncated = github.Ptr(true)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(tree)
+	})
+}
+
+var _ = Describe("TreeFS", func() {
+	var (
+		repo     fstest.MapFS
+		requests []string
+	)
+
+	BeforeEach(func() {
+		repo = fstest.MapFS{
+			"README.md":       {Data: []byte("# repo")},
+			"cmd/main.go":     {Data: []byte("package main")},
+			"internal/a/a.go": {Data: []byte("package a")},
+			"internal/b/b.go": {Data: []byte("package b")},
+		}
+		requests = nil
+	})
+
+	tree := func(truncated bool) *ghfs.TreeFS {
+		GinkgoHelper()
+		handler := treesHandler(repo, truncated)
+		return ghfs.Tree("owner", "repo", "main", ghfs.WithClient(testClient(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, path.Base(path.Dir(r.URL.Path)))
+				handler.ServeHTTP(w, r)
+			}),
+		)))
+	}
+
+	It("should walk the repository with a single request", func() {
+		fsys := tree(false)
+
+		var paths []string
+		err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+			paths = append(paths, p)
+			return err
+		})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(paths).To(Equal([]string{
+			".", "README.md", "cmd", "cmd/main.go",
+			"internal", "internal/a", "internal/a/a.go", "internal/b", "internal/b/b.go",
+		}))
+		Expect(requests).To(Equal([]string{"trees"}))
+	})
+
+	It("should stat files without fetching their content", func() {
+		fsys := tree(false)
+
+		info, err := fs.Stat(fsys, "internal/a/a.go")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Name()).To(Equal("a.go"))
+		Expect(info.Size()).To(Equal(int64(9)))
+		Expect(requests).To(Equal([]string{"trees"}))
+	})
+
+	It("should version entries by their SHA from the index", func() {
+		fsys := tree(false)
+
+		version, err := fsys.Version("internal/a/a.go")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(version).To(Equal(hex.EncodeToString([]byte("internal/a/a.go"))))
+		Expect(requests).To(Equal([]string{"trees"}))
+	})
+
+	It("should fetch blobs lazily", func() {
+		fsys := tree(false)
+
+		data, err := fs.ReadFile(fsys, "cmd/main.go")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("package main"))
+		Expect(requests).To(Equal([]string{"trees", "blobs"}))
+	})
+
+	It("should not fetch submodules as blobs", func() {
+		repo["vendor/lib"] = &fstest.MapFile{Mode: fs.ModeIrregular}
+		fsys := tree(false)
+
+		_, err := fsys.Open("vendor/lib")
+
+		Expect(err).To(MatchError(fs.ErrInvalid))
+		Expect(requests).NotTo(ContainElement("blobs"))
+	})
+
+	It("should return ErrNotExist for missing paths", func() {
+		fsys := tree(false)
+
+		_, err := fsys.Open("cmd/missing.go")
+		Expect(err).To(MatchError(fs.ErrNotExist))
+		_, err = fsys.Stat("README.md/child")
+		Expect(err).To(MatchError(fs.ErrNotExist))
+	})
+
+	It("should fall back to fetching directories when the tree is truncated", func() {
+		fsys := tree(true)
+
+		entries, err := fs.ReadDir(fsys, "internal/b")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Name()).To(Equal("b.go"))
+		Expect(requests).To(Equal([]string{"trees", "trees", "trees", "trees"}))
+
+		data, err := fs.ReadFile(fsys, "internal/b/b.go")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("package b"))
+	})
+
+	It("should pass fstest.TestFS", func() {
+		Expect(fstest.TestFS(tree(false), "README.md", "cmd/main.go", "internal/a/a.go")).To(Succeed())
+	})
+
+	It("should pass fstest.TestFS when the tree is truncated", func() {
+		Expect(fstest.TestFS(tree(true), "README.md", "cmd/main.go", "internal/a/a.go")).To(Succeed())
+	})
+})
@@ -33,6 +33,14 @@ func OpenReleaseByTag(fsys ihfs.FS, owner, repo, tag string) (*github.Repository
 	return openDecode[github.RepositoryRelease](fsys, releasePathByTag(owner, repo, tag))
 }
 
+func OpenGist(fsys ihfs.FS, id string) (*github.Gist, error) {
+	return openDecode[github.Gist](fsys, gistPath(id))
+}
+
+func OpenGistRevision(fsys ihfs.FS, id, sha string) (*github.Gist, error) {
+	return openDecode[github.Gist](fsys, gistRevisionPath(id, sha))
+}
+
 func OpenAsset(fsys ihfs.FS, owner, repo string, id int64) (*github.ReleaseAsset, error) {
 	return openDecode[github.ReleaseAsset](fsys, assetPath(owner, repo, id))
 }
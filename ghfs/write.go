@@ -0,0 +1,239 @@
+package ghfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+
+	"github.com/google/go-github/v84/github"
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/op"
+)
+
+// Commit describes the commit created by each write to a repository.
+// Empty fields fall back to the defaults of the [Fs], and then to the GitHub defaults.
+type Commit struct {
+	// Message is the commit message. It defaults to a message describing the change, e.g. "Update README.md".
+	Message string
+
+	// Author is the commit author. It defaults to the authenticated user.
+	Author *github.CommitAuthor
+
+	// Committer is the committer. It defaults to the author.
+	Committer *github.CommitAuthor
+}
+
+type commitKey struct{}
+
+// ContextWithCommit returns a copy of ctx that carries c. Return it from a [ContextFunc]
+// to describe the commit created by a single write.
+func ContextWithCommit(ctx context.Context, c Commit) context.Context {
+	return context.WithValue(ctx, commitKey{}, c)
+}
+
+// CommitFromContext returns the [Commit] carried by ctx, if any.
+func CommitFromContext(ctx context.Context) (Commit, bool) {
+	c, ok := ctx.Value(commitKey{}).(Commit)
+	return c, ok
+}
+
+// WriteFile implements [ihfs.WriteFileFS] for content paths, e.g. "github.com/owner/repo/blob/main/README.md",
+// by committing data to the branch with the contents API. perm is ignored.
+func (f *Fs) WriteFile(name string, data []byte, perm ihfs.FileMode) error {
+	ctx := f.context(op.WriteFile{Name: name, Data: data, Perm: perm})
+	p, err := f.contentTarget(name)
+	if err != nil {
+		return writeErr("write", name, err)
+	}
+	return f.on(p).put(ctx, "write", name, p.owner, p.repo, p.branch, path.Join(p.content...), data)
+}
+
+// Create implements [ihfs.CreateFS] for content paths. The returned file buffers everything written to it
+// and commits it to the branch when it is closed.
+func (f *Fs) Create(name string) (ihfs.File, error) {
+	p, err := f.contentTarget(name)
+	if err != nil {
+		return nil, writeErr("create", name, err)
+	}
+	return &writer{name: name, commit: func(data []byte) error {
+		ctx := f.context(op.WriteFile{Name: name, Data: data})
+		return f.on(p).put(ctx, "create", name, p.owner, p.repo, p.branch, path.Join(p.content...), data)
+	}}, nil
+}
+
+// Remove implements [ihfs.RemoveFS] for content paths by deleting the file from the branch.
+// Directories cannot be removed.
+func (f *Fs) Remove(name string) error {
+	ctx := f.context(op.Remove{Name: name})
+	p, err := f.contentTarget(name)
+	if err != nil {
+		return writeErr("remove", name, err)
+	}
+	return f.on(p).delete(ctx, name, p.owner, p.repo, p.branch, path.Join(p.content...))
+}
+
+// WriteFile implements [ihfs.WriteFileFS] by committing data to the branch of r. perm is ignored.
+func (r *RepoFS) WriteFile(name string, data []byte, perm ihfs.FileMode) error {
+	ctx := r.fs.context(op.WriteFile{Name: name, Data: data, Perm: perm})
+	if !fs.ValidPath(name) || name == "." {
+		return writeErr("write", name, ihfs.ErrInvalid)
+	}
+	return r.fs.put(ctx, "write", name, r.owner, r.repo, r.ref, name, data)
+}
+
+// Create implements [ihfs.CreateFS]. The returned file buffers everything written to it
+// and commits it to the branch of r when it is closed.
+func (r *RepoFS) Create(name string) (ihfs.File, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return nil, writeErr("create", name, ihfs.ErrInvalid)
+	}
+	return &writer{name: name, commit: func(data []byte) error {
+		ctx := r.fs.context(op.WriteFile{Name: name, Data: data})
+		return r.fs.put(ctx, "create", name, r.owner, r.repo, r.ref, name, data)
+	}}, nil
+}
+
+// Remove implements [ihfs.RemoveFS] by deleting the file from the branch of r.
+// Directories cannot be removed.
+func (r *RepoFS) Remove(name string) error {
+	ctx := r.fs.context(op.Remove{Name: name})
+	if !fs.ValidPath(name) || name == "." {
+		return writeErr("remove", name, ihfs.ErrInvalid)
+	}
+	return r.fs.delete(ctx, name, r.owner, r.repo, r.ref, name)
+}
+
+// put creates or updates the file at p in owner/repo on branch.
+func (f *Fs) put(ctx context.Context, opName, name, owner, repo, branch, p string, data []byte) error {
+	sha, err := f.sha(ctx, owner, repo, branch, p)
+	if err != nil {
+		return writeErr(opName, name, err)
+	}
+
+	msg := "Create " + p
+	if sha != nil {
+		msg = "Update " + p
+	}
+	opts := f.commitOptions(ctx, msg, branch)
+	opts.Content, opts.SHA = data, sha
+
+	if _, _, err := f.client.Repositories.CreateFile(ctx, owner, repo, p, opts); err != nil {
+		return writeErr(opName, name, notExist(err))
+	}
+	return nil
+}
+
+// delete removes the file at p in owner/repo on branch.
+func (f *Fs) delete(ctx context.Context, name, owner, repo, branch, p string) error {
+	sha, err := f.sha(ctx, owner, repo, branch, p)
+	if err != nil {
+		return writeErr("remove", name, err)
+	}
+	if sha == nil {
+		return writeErr("remove", name, fs.ErrNotExist)
+	}
+
+	opts := f.commitOptions(ctx, "Delete "+p, branch)
+	opts.SHA = sha
+	if _, _, err := f.client.Repositories.DeleteFile(ctx, owner, repo, p, opts); err != nil {
+		return writeErr("remove", name, notExist(err))
+	}
+	return nil
+}
+
+// sha returns the blob SHA of the file at p, or nil if it does not exist.
+func (f *Fs) sha(ctx context.Context, owner, repo, branch, p string) (*string, error) {
+	file, dir, resp, err := f.client.Repositories.GetContents(ctx, owner, repo, p,
+		&github.RepositoryContentGetOptions{Ref: branch},
+	)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if dir != nil || file == nil || file.GetType() != "file" {
+		return nil, ihfs.ErrInvalid
+	}
+	return file.SHA, nil
+}
+
+// commitOptions returns the commit options for a write to branch, using the [Commit] from ctx,
+// then the default commit of f and finally msg.
+func (f *Fs) commitOptions(ctx context.Context, msg, branch string) *github.RepositoryContentFileOptions {
+	c := f.commit
+	if fromCtx, ok := CommitFromContext(ctx); ok {
+		if fromCtx.Message != "" {
+			c.Message = fromCtx.Message
+		}
+		if fromCtx.Author != nil {
+			c.Author = fromCtx.Author
+		}
+		if fromCtx.Committer != nil {
+			c.Committer = fromCtx.Committer
+		}
+	}
+	if c.Message == "" {
+		c.Message = msg
+	}
+
+	opts := &github.RepositoryContentFileOptions{
+		Message:   github.Ptr(c.Message),
+		Author:    c.Author,
+		Committer: c.Committer,
+	}
+	if branch != "" {
+		opts.Branch = github.Ptr(branch)
+	}
+	return opts
+}
+
+// contentTarget parses name as a path to a file in a repository.
+func (f *Fs) contentTarget(name string) (Path, error) {
+	p, err := parse(name, f.hosts)
+	if err != nil {
+		return Path{}, err
+	}
+	if p.owner == "" || p.repo == "" || len(p.content) == 0 {
+		return Path{}, fmt.Errorf("not a content path: %w", ihfs.ErrInvalid)
+	}
+	return p, nil
+}
+
+func writeErr(op, name string, err error) error {
+	return &ihfs.PathError{Op: op, Path: name, Err: err}
+}
+
+// writer is a [File] that buffers writes and commits them on Close.
+type writer struct {
+	name   string
+	buf    bytes.Buffer
+	commit func([]byte) error
+	closed bool
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, writeErr("write", w.name, fs.ErrClosed)
+	}
+	return w.buf.Write(p)
+}
+
+func (w *writer) Read([]byte) (int, error) {
+	return 0, writeErr("read", w.name, fs.ErrInvalid)
+}
+
+func (w *writer) Stat() (ihfs.FileInfo, error) {
+	return &FileInfo{name: path.Base(w.name), size: int64(w.buf.Len())}, nil
+}
+
+func (w *writer) Close() error {
+	if w.closed {
+		return writeErr("close", w.name, fs.ErrClosed)
+	}
+	w.closed = true
+	return w.commit(w.buf.Bytes())
+}
@@ -0,0 +1,221 @@
+package ghfs_test
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/google/go-github/v84/github"
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/ghfs"
+)
+
+// contentsStore is an in-memory stand-in for the contents API of owner/repo.
+type contentsStore struct {
+	mu      sync.Mutex
+	files   map[string][]byte
+	commits []*github.RepositoryContentFileOptions
+}
+
+func (s *contentsStore) sha(p string) string {
+	h := sha1.Sum(s.files[p])
+	return hex.EncodeToString(h[:])
+}
+
+func (s *contentsStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := strings.CutPrefix(r.URL.Path, "/repos/owner/repo/contents/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	notFound := func() {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "Not Found"}`))
+	}
+
+	if r.Method == http.MethodGet {
+		if _, ok := s.files[p]; !ok {
+			notFound()
+			return
+		}
+		_ = json.NewEncoder(w).Encode(github.RepositoryContent{
+			Name: github.Ptr(p),
+			Type: github.Ptr("file"),
+			SHA:  github.Ptr(s.sha(p)),
+		})
+		return
+	}
+
+	var opts github.RepositoryContentFileOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if _, ok := s.files[p]; ok && opts.GetSHA() != s.sha(p) {
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"message": "sha does not match"}`))
+		return
+	}
+	s.commits = append(s.commits, &opts)
+
+	switch r.Method {
+	case http.MethodPut:
+		s.files[p] = opts.Content
+	case http.MethodDelete:
+		if _, ok := s.files[p]; !ok {
+			notFound()
+			return
+		}
+		delete(s.files, p)
+	}
+	_, _ = w.Write([]byte(`{}`))
+}
+
+var _ = Describe("Write", func() {
+	var (
+		store *contentsStore
+		repo  *ghfs.RepoFS
+	)
+
+	BeforeEach(func() {
+		store = &contentsStore{files: map[string][]byte{
+			"README.md": []byte("# repo"),
+		}}
+		repo = ghfs.Repo("owner", "repo", "main", ghfs.WithClient(testClient(store)))
+	})
+
+	It("should create files", func() {
+		Expect(repo.WriteFile("docs/new.md", []byte("new"), 0644)).To(Succeed())
+
+		Expect(store.files).To(HaveKeyWithValue("docs/new.md", []byte("new")))
+		Expect(store.commits).To(HaveLen(1))
+		Expect(store.commits[0].GetMessage()).To(Equal("Create docs/new.md"))
+		Expect(store.commits[0].GetBranch()).To(Equal("main"))
+		Expect(store.commits[0].SHA).To(BeNil())
+	})
+
+	It("should update existing files", func() {
+		Expect(repo.WriteFile("README.md", []byte("updated"), 0644)).To(Succeed())
+
+		Expect(store.files).To(HaveKeyWithValue("README.md", []byte("updated")))
+		Expect(store.commits[0].GetMessage()).To(Equal("Update README.md"))
+	})
+
+	It("should commit files written with Create on Close", func() {
+		f, err := repo.Create("main.go")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = io.WriteString(f.(io.Writer), "package ")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.WriteString(f.(io.Writer), "main")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.files).NotTo(HaveKey("main.go"))
+
+		Expect(f.Close()).To(Succeed())
+		Expect(store.files).To(HaveKeyWithValue("main.go", []byte("package main")))
+		Expect(f.Close()).To(MatchError(fs.ErrClosed))
+	})
+
+	It("should work with ihfs.WriteFile", func() {
+		Expect(ihfs.WriteFile(repo, "config.yaml", []byte("a: b"), 0644)).To(Succeed())
+
+		Expect(store.files).To(HaveKeyWithValue("config.yaml", []byte("a: b")))
+	})
+
+	It("should remove files", func() {
+		Expect(repo.Remove("README.md")).To(Succeed())
+
+		Expect(store.files).NotTo(HaveKey("README.md"))
+		Expect(store.commits[0].GetMessage()).To(Equal("Delete README.md"))
+	})
+
+	It("should return ErrNotExist when removing missing files", func() {
+		Expect(repo.Remove("missing.md")).To(MatchError(fs.ErrNotExist))
+	})
+
+	It("should reject invalid paths", func() {
+		Expect(repo.WriteFile("/README.md", nil, 0644)).To(MatchError(ihfs.ErrInvalid))
+		Expect(repo.Remove(".")).To(MatchError(ihfs.ErrInvalid))
+		_, err := repo.Create("../x")
+		Expect(err).To(MatchError(ihfs.ErrInvalid))
+	})
+
+	It("should use the default commit", func() {
+		author := &github.CommitAuthor{Name: github.Ptr("bot"), Email: github.Ptr("bot@example.com")}
+		repo = ghfs.Repo("owner", "repo", "main",
+			ghfs.WithClient(testClient(store)),
+			ghfs.WithCommit(ghfs.Commit{Message: "chore: sync", Author: author}),
+		)
+
+		Expect(repo.WriteFile("README.md", []byte("sync"), 0644)).To(Succeed())
+
+		Expect(store.commits[0].GetMessage()).To(Equal("chore: sync"))
+		Expect(store.commits[0].Author.GetName()).To(Equal("bot"))
+	})
+
+	It("should prefer the commit from the context", func() {
+		repo = ghfs.Repo("owner", "repo", "main",
+			ghfs.WithClient(testClient(store)),
+			ghfs.WithCommit(ghfs.Commit{Message: "default"}),
+			ghfs.WithContextFunc(func(_ *ghfs.Fs, o ihfs.Operation) context.Context {
+				return ghfs.ContextWithCommit(context.Background(), ghfs.Commit{
+					Message: "write " + o.Subject(),
+				})
+			}),
+		)
+
+		Expect(repo.WriteFile("README.md", []byte("ctx"), 0644)).To(Succeed())
+
+		Expect(store.commits[0].GetMessage()).To(Equal("write README.md"))
+	})
+
+	Describe("Fs", func() {
+		var fsys *ghfs.Fs
+
+		BeforeEach(func() {
+			fsys = ghfs.New(ghfs.WithClient(testClient(store)))
+		})
+
+		It("should write content paths", func() {
+			Expect(fsys.WriteFile("github.com/owner/repo/blob/dev/a.txt", []byte("a"), 0644)).To(Succeed())
+
+			Expect(store.files).To(HaveKeyWithValue("a.txt", []byte("a")))
+			Expect(store.commits[0].GetBranch()).To(Equal("dev"))
+		})
+
+		It("should create content paths", func() {
+			f, err := fsys.Create("github.com/owner/repo/blob/main/b.txt")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = f.(io.Writer).Write([]byte("b"))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(f.Close()).To(Succeed())
+			Expect(store.files).To(HaveKeyWithValue("b.txt", []byte("b")))
+		})
+
+		It("should remove content paths", func() {
+			Expect(fsys.Remove("github.com/owner/repo/blob/main/README.md")).To(Succeed())
+
+			Expect(store.files).NotTo(HaveKey("README.md"))
+		})
+
+		It("should reject paths that are not repository contents", func() {
+			Expect(fsys.WriteFile("github.com/owner/repo", nil, 0644)).To(MatchError(ihfs.ErrInvalid))
+			_, err := fsys.Create("users/owner")
+			Expect(err).To(MatchError(ihfs.ErrInvalid))
+			Expect(fsys.Remove("github.com/owner")).To(MatchError(ihfs.ErrInvalid))
+		})
+	})
+})
@@ -0,0 +1,5 @@
+// Package httpfs provides a read-only file system over plain HTTP(S) servers, such as artifact
+// servers and static mirrors. Files are resolved relative to a base URL. Stat uses HEAD requests,
+// files support random access with Range requests, and directories can be listed by plugging in
+// an [IndexParser] for the server's directory listings.
+package httpfs
@@ -0,0 +1,150 @@
+package httpfs
+
+import (
+	"errors"
+	"io"
+
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/internal/httprange"
+)
+
+var errNoVersion = errors.New("no ETag or Last-Modified header")
+
+// File is a file or directory opened from an [Fs].
+//
+// Files are read from the response to the GET request that opened them. After a Seek, reads
+// continue from a single Range request for the rest of the file, and ReadAt uses Range requests
+// that fetch a small read-ahead buffer at a time. Servers that ignore Range are read from the start.
+type File struct {
+	name   string
+	body   io.ReadCloser
+	info   *FileInfo
+	ra     *httprange.Reader
+	offset int64
+
+	entries []ihfs.DirEntry
+	listed  bool
+	closed  bool
+}
+
+// Close implements [ihfs.File].
+func (f *File) Close() error {
+	if f.closed {
+		return f.error("close", ihfs.ErrClosed)
+	}
+	f.closed = true
+	if f.body == nil {
+		return nil
+	}
+	return f.body.Close()
+}
+
+// Stat implements [ihfs.File].
+func (f *File) Stat() (ihfs.FileInfo, error) {
+	info := *f.info
+	return &info, nil
+}
+
+// Read implements [io.Reader].
+func (f *File) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, f.error("read", ihfs.ErrClosed)
+	}
+	if f.info.isDir {
+		return 0, f.error("read", ihfs.ErrInvalid)
+	}
+	if f.body == nil {
+		body, err := f.ra.Stream(f.offset)
+		if err != nil {
+			return 0, f.error("read", err)
+		}
+		f.body = body
+	}
+
+	n, err := f.body.Read(p)
+	f.offset += int64(n)
+	return n, err
+}
+
+// ReadAt implements [io.ReaderAt] with Range requests.
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	if f.closed {
+		return 0, f.error("readat", ihfs.ErrClosed)
+	}
+	if f.info.isDir {
+		return 0, f.error("readat", ihfs.ErrInvalid)
+	}
+
+	n, err := f.ra.ReadAt(p, off)
+	if err != nil && err != io.EOF {
+		return n, f.error("readat", err)
+	}
+	return n, err
+}
+
+// Seek implements [io.Seeker]. The response that opened the file is closed once the file
+// is read out of order, and the next Read requests the rest of the file from the new offset.
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	if f.closed {
+		return 0, f.error("seek", ihfs.ErrClosed)
+	}
+	if f.info.isDir {
+		return 0, f.error("seek", ihfs.ErrInvalid)
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		size, err := f.ra.Size()
+		if err != nil {
+			return 0, f.error("seek", err)
+		}
+		offset += size
+	default:
+		return 0, f.error("seek", ihfs.ErrInvalid)
+	}
+	if offset < 0 {
+		return 0, f.error("seek", httprange.ErrNegativeOffset)
+	}
+
+	if offset != f.offset && f.body != nil {
+		_ = f.body.Close()
+		f.body = nil
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// ReadDir implements [fs.ReadDirFile]. Directories can only be listed when the [Fs]
+// has an [IndexParser].
+func (f *File) ReadDir(n int) ([]ihfs.DirEntry, error) {
+	if f.closed {
+		return nil, f.error("readdir", ihfs.ErrClosed)
+	}
+	if !f.info.isDir {
+		return nil, f.error("readdir", ihfs.ErrInvalid)
+	}
+	if !f.listed {
+		return nil, f.error("readdir", ihfs.ErrNotImplemented)
+	}
+
+	if n <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	n = min(n, len(f.entries))
+	entries := f.entries[:n]
+	f.entries = f.entries[n:]
+	return entries, nil
+}
+
+func (f *File) error(op string, err error) error {
+	return pathErr(op, f.name, err)
+}
@@ -0,0 +1,159 @@
+package httpfs_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/httpfs"
+)
+
+var _ = Describe("File", func() {
+	var (
+		content []byte
+		ranges  []string
+		fsys    *httpfs.Fs
+	)
+
+	BeforeEach(func() {
+		buf := &bytes.Buffer{}
+		zw := zip.NewWriter(buf)
+		w, err := zw.Create("bin/tool")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = w.Write([]byte("#!/bin/sh"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(zw.Close()).To(Succeed())
+		content, ranges = buf.Bytes(), nil
+
+		fsys = httpfs.New(serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ranges = append(ranges, r.Header.Get("Range"))
+			http.ServeContent(w, r, "tool.zip", time.Time{}, bytes.NewReader(content))
+		})))
+	})
+
+	It("should read at offsets with Range requests", func() {
+		f, err := fsys.Open("tool.zip")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(f.Close)
+
+		p := make([]byte, 4)
+		n, err := f.(io.ReaderAt).ReadAt(p, 1)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(4))
+		Expect(p).To(Equal(content[1:5]))
+		Expect(ranges).To(Equal([]string{"", fmt.Sprintf("bytes=1-%d", len(content)-1)}))
+	})
+
+	It("should read nearby offsets from the read-ahead buffer", func() {
+		f, err := fsys.Open("tool.zip")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(f.Close)
+
+		for off := range int64(8) {
+			_, err := f.(io.ReaderAt).ReadAt(make([]byte, 2), off)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		Expect(ranges).To(HaveLen(2))
+	})
+
+	It("should return io.EOF when reading past the end", func() {
+		f, err := fsys.Open("tool.zip")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(f.Close)
+
+		p := make([]byte, 10)
+		n, err := f.(io.ReaderAt).ReadAt(p, int64(len(content)-4))
+
+		Expect(err).To(MatchError(io.EOF))
+		Expect(n).To(Equal(4))
+		Expect(p[:n]).To(Equal(content[len(content)-4:]))
+	})
+
+	It("should read zip archives", func() {
+		f, err := fsys.Open("tool.zip")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(f.Close)
+		info, err := f.Stat()
+		Expect(err).NotTo(HaveOccurred())
+
+		zr, err := zip.NewReader(f.(io.ReaderAt), info.Size())
+		Expect(err).NotTo(HaveOccurred())
+		rc, err := zr.Open("bin/tool")
+		Expect(err).NotTo(HaveOccurred())
+		data, err := io.ReadAll(rc)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("#!/bin/sh"))
+	})
+
+	It("should read from the offset after seeking", func() {
+		f, err := fsys.Open("tool.zip")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(f.Close)
+
+		off, err := f.(io.Seeker).Seek(-4, io.SeekEnd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(off).To(Equal(int64(len(content) - 4)))
+		data, err := io.ReadAll(f)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(Equal(content[len(content)-4:]))
+	})
+
+	It("should read sequentially after seeking with a single request", func() {
+		f, err := fsys.Open("tool.zip")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(f.Close)
+
+		_, err = f.(io.Seeker).Seek(1, io.SeekStart)
+		Expect(err).NotTo(HaveOccurred())
+		var data []byte
+		p := make([]byte, 3)
+		for {
+			n, err := f.Read(p)
+			data = append(data, p[:n]...)
+			if err == io.EOF {
+				break
+			}
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		Expect(data).To(Equal(content[1:]))
+		Expect(ranges).To(Equal([]string{"", "bytes=1-"}))
+	})
+
+	It("should read at offsets from servers that ignore Range", func() {
+		fsys := httpfs.New(serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.Copy(w, strings.NewReader("0123456789"))
+		})))
+		f, err := fsys.Open("digits.txt")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(f.Close)
+
+		p := make([]byte, 3)
+		n, err := f.(io.ReaderAt).ReadAt(p, 5)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(p[:n])).To(Equal("567"))
+	})
+
+	It("should return ErrClosed after Close", func() {
+		f, err := fsys.Open("tool.zip")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+
+		_, err = f.Read(make([]byte, 1))
+
+		Expect(err).To(MatchError(ihfs.ErrClosed))
+	})
+})
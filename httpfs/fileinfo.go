@@ -0,0 +1,68 @@
+package httpfs
+
+import (
+	"io/fs"
+	"net/http"
+	"time"
+)
+
+// FileInfo describes a file from the headers of the response to a GET or HEAD request.
+// Sys returns the [http.Header] of the response.
+type FileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	isDir   bool
+	header  http.Header
+}
+
+// Name implements [fs.FileInfo].
+func (fi *FileInfo) Name() string { return fi.name }
+
+// Size implements [fs.FileInfo]. It is the Content-Length of the response, or -1 if it was not sent.
+func (fi *FileInfo) Size() int64 { return fi.size }
+
+// Mode implements [fs.FileInfo].
+func (fi *FileInfo) Mode() fs.FileMode {
+	if fi.isDir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+// ModTime implements [fs.FileInfo]. It is the Last-Modified time of the response, if it was sent.
+func (fi *FileInfo) ModTime() time.Time { return fi.modTime }
+
+// IsDir implements [fs.FileInfo].
+func (fi *FileInfo) IsDir() bool { return fi.isDir }
+
+// Sys implements [fs.FileInfo].
+func (fi *FileInfo) Sys() any { return fi.header }
+
+// DirEntry is an entry of a directory listing. Its [fs.FileInfo] is requested with Stat
+// when Info is called.
+type DirEntry struct {
+	fs    *Fs
+	path  string
+	name  string
+	isDir bool
+}
+
+// Name implements [fs.DirEntry].
+func (e *DirEntry) Name() string { return e.name }
+
+// IsDir implements [fs.DirEntry].
+func (e *DirEntry) IsDir() bool { return e.isDir }
+
+// Type implements [fs.DirEntry].
+func (e *DirEntry) Type() fs.FileMode {
+	if e.isDir {
+		return fs.ModeDir
+	}
+	return 0
+}
+
+// Info implements [fs.DirEntry].
+func (e *DirEntry) Info() (fs.FileInfo, error) {
+	return e.fs.Stat(e.path)
+}
@@ -0,0 +1,221 @@
+package httpfs
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/unmango/go/fopt"
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/internal/httprange"
+)
+
+// Fs is a read-only file system over the files served below a base URL.
+//
+// Paths are resolved relative to the base URL, with each element path escaped. A URL is a directory
+// if it is the base URL or if the server redirects it to a URL ending with a slash, which is how
+// most servers treat directories.
+type Fs struct {
+	base   *url.URL
+	client *http.Client
+	index  IndexParser
+}
+
+// New creates a file system for the files below base.
+func New(base *url.URL, options ...Option) *Fs {
+	b := *base
+	if !strings.HasSuffix(b.Path, "/") {
+		b.Path += "/"
+		if b.RawPath != "" {
+			b.RawPath += "/"
+		}
+	}
+
+	f := &Fs{base: &b, client: http.DefaultClient}
+	fopt.ApplyAll(f, options)
+
+	return f
+}
+
+// Name implements [ihfs.FS].
+func (*Fs) Name() string {
+	return "http"
+}
+
+// Open implements [ihfs.FS]. Files are requested with a GET request and read as the response is
+// received. Directories can only be read with an [IndexParser] configured with [WithIndex].
+func (f *Fs) Open(name string) (ihfs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, pathErr("open", name, ihfs.ErrInvalid)
+	}
+
+	resp, err := f.do(context.Background(), http.MethodGet, name, nil)
+	if err != nil {
+		return nil, pathErr("open", name, err)
+	}
+
+	info := f.fileInfo(name, resp)
+	if !info.isDir {
+		return &File{
+			name: name,
+			body: resp.Body,
+			info: info,
+			ra:   httprange.New(f.ranges(name), info.size),
+		}, nil
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+	if f.index == nil {
+		return &File{name: name, info: info}, nil
+	}
+
+	names, err := f.index(resp.Body)
+	if err != nil {
+		return nil, pathErr("open", name, err)
+	}
+	entries := make([]ihfs.DirEntry, 0, len(names))
+	for _, n := range names {
+		entry, ok := f.dirEntry(name, n)
+		if ok {
+			entries = append(entries, entry)
+		}
+	}
+	return &File{name: name, info: info, entries: entries, listed: true}, nil
+}
+
+// Stat implements [ihfs.StatFS] with a HEAD request.
+func (f *Fs) Stat(name string) (ihfs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, pathErr("stat", name, ihfs.ErrInvalid)
+	}
+
+	resp, err := f.do(context.Background(), http.MethodHead, name, nil)
+	if err != nil {
+		return nil, pathErr("stat", name, err)
+	}
+	_ = resp.Body.Close()
+
+	return f.fileInfo(name, resp), nil
+}
+
+// Version implements [ihfs.VersionFS] with the ETag of a HEAD request, or its
+// Last-Modified header if the server does not send an ETag.
+func (f *Fs) Version(name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", pathErr("version", name, ihfs.ErrInvalid)
+	}
+
+	resp, err := f.do(context.Background(), http.MethodHead, name, nil)
+	if err != nil {
+		return "", pathErr("version", name, err)
+	}
+	_ = resp.Body.Close()
+
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		return etag, nil
+	}
+	if modified := resp.Header.Get("Last-Modified"); modified != "" {
+		return modified, nil
+	}
+	return "", pathErr("version", name, errNoVersion)
+}
+
+// URL returns the URL of name.
+func (f *Fs) URL(name string) *url.URL {
+	if name == "." {
+		u := *f.base
+		return &u
+	}
+	return f.base.JoinPath(strings.Split(name, "/")...)
+}
+
+// do sends a request for name and returns the response if it was successful.
+func (f *Fs) do(ctx context.Context, method, name string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, f.URL(name).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	_ = resp.Body.Close()
+	return nil, statusErr(resp)
+}
+
+// ranges returns the [httprange.Fetch] that requests ranges of name.
+func (f *Fs) ranges(name string) httprange.Fetch {
+	return func(rng string) (*http.Response, error) {
+		return f.do(context.Background(), http.MethodGet, name, http.Header{"Range": {rng}})
+	}
+}
+
+// fileInfo describes name from the headers of resp.
+func (f *Fs) fileInfo(name string, resp *http.Response) *FileInfo {
+	info := &FileInfo{
+		name:   baseName(name),
+		size:   resp.ContentLength,
+		header: resp.Header,
+		isDir:  name == "." || strings.HasSuffix(resp.Request.URL.Path, "/"),
+	}
+	if info.isDir {
+		info.size = 0
+	}
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.modTime = modTime
+	}
+	return info
+}
+
+// dirEntry returns the entry of the directory dir for a name from its listing.
+// Names that are not direct children of dir, such as links to parent directories, are skipped.
+func (f *Fs) dirEntry(dir, name string) (*DirEntry, bool) {
+	name, isDir := strings.CutSuffix(name, "/")
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+		return nil, false
+	}
+
+	p := name
+	if dir != "." {
+		p = dir + "/" + name
+	}
+	return &DirEntry{fs: f, path: p, name: name, isDir: isDir}, true
+}
+
+func baseName(name string) string {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+func statusErr(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusRequestedRangeNotSatisfiable:
+		return httprange.ErrNotSatisfiable
+	case http.StatusNotFound, http.StatusGone:
+		return ihfs.ErrNotExist
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ihfs.ErrPermission
+	default:
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+}
+
+func pathErr(op, name string, err error) error {
+	return &ihfs.PathError{Op: op, Path: name, Err: err}
+}
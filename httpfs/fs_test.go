@@ -0,0 +1,201 @@
+package httpfs_test
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/unstoppablemango/ihfs"
+	"github.com/unstoppablemango/ihfs/httpfs"
+)
+
+var modTime = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+var files = fstest.MapFS{
+	"README.md":           {Data: []byte("# mirror"), ModTime: modTime},
+	"releases/v1/app.tgz": {Data: []byte("v1 archive"), ModTime: modTime},
+	"releases/v2/app.tgz": {Data: []byte("v2 archive"), ModTime: modTime},
+	"with space.txt":      {Data: []byte("spaced"), ModTime: modTime},
+}
+
+// serve starts a file server for fsys and returns its URL.
+func serve(h http.Handler) *url.URL {
+	s := httptest.NewServer(h)
+	DeferCleanup(s.Close)
+	u, err := url.Parse(s.URL)
+	Expect(err).NotTo(HaveOccurred())
+	return u
+}
+
+var _ = Describe("Fs", func() {
+	var base *url.URL
+
+	BeforeEach(func() {
+		base = serve(http.FileServer(http.FS(files)))
+	})
+
+	It("should read files", func() {
+		fsys := httpfs.New(base)
+
+		data, err := fs.ReadFile(fsys, "releases/v1/app.tgz")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("v1 archive"))
+	})
+
+	It("should escape paths", func() {
+		fsys := httpfs.New(base)
+
+		data, err := fs.ReadFile(fsys, "with space.txt")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("spaced"))
+	})
+
+	It("should resolve paths relative to the base path", func() {
+		fsys := httpfs.New(base.JoinPath("releases"))
+
+		data, err := fs.ReadFile(fsys, "v2/app.tgz")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("v2 archive"))
+	})
+
+	It("should stat files with HEAD", func() {
+		var methods []string
+		base := serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			methods = append(methods, r.Method)
+			http.FileServer(http.FS(files)).ServeHTTP(w, r)
+		}))
+		fsys := httpfs.New(base)
+
+		info, err := fsys.Stat("README.md")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Name()).To(Equal("README.md"))
+		Expect(info.Size()).To(Equal(int64(8)))
+		Expect(info.ModTime()).To(BeTemporally("==", modTime))
+		Expect(info.IsDir()).To(BeFalse())
+		Expect(info.Sys()).To(BeAssignableToTypeOf(http.Header{}))
+		Expect(methods).To(Equal([]string{http.MethodHead}))
+	})
+
+	It("should stat directories", func() {
+		fsys := httpfs.New(base)
+
+		info, err := fsys.Stat("releases")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.IsDir()).To(BeTrue())
+		Expect(info.Mode()).To(Equal(fs.ModeDir | 0o555))
+	})
+
+	It("should version files by their ETag", func() {
+		fsys := httpfs.New(serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Method).To(Equal(http.MethodHead))
+			w.Header().Set("ETag", `"abc123"`)
+			w.Header().Set("Last-Modified", modTime.Format(http.TimeFormat))
+		})))
+
+		version, err := fsys.Version("README.md")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(version).To(Equal(`"abc123"`))
+	})
+
+	It("should version files by their modification time without an ETag", func() {
+		fsys := httpfs.New(base)
+
+		version, err := fsys.Version("README.md")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(version).To(Equal(modTime.Format(http.TimeFormat)))
+	})
+
+	It("should fail to version files without validators", func() {
+		fsys := httpfs.New(serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+		_, err := fsys.Version("README.md")
+
+		var pathErr *fs.PathError
+		Expect(err).To(BeAssignableToTypeOf(pathErr))
+		Expect(err.Error()).To(ContainSubstring("ETag"))
+	})
+
+	It("should return ErrNotExist for missing files", func() {
+		fsys := httpfs.New(base)
+
+		_, err := fsys.Open("missing.txt")
+		Expect(err).To(MatchError(ihfs.ErrNotExist))
+
+		_, err = fsys.Stat("missing.txt")
+		Expect(err).To(MatchError(ihfs.ErrNotExist))
+	})
+
+	It("should return ErrPermission for forbidden files", func() {
+		base := serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		fsys := httpfs.New(base)
+
+		_, err := fsys.Open("secret.txt")
+
+		Expect(err).To(MatchError(ihfs.ErrPermission))
+	})
+
+	It("should reject invalid paths", func() {
+		fsys := httpfs.New(base)
+
+		_, err := fsys.Open("../README.md")
+
+		Expect(err).To(MatchError(ihfs.ErrInvalid))
+	})
+
+	It("should not list directories without an index parser", func() {
+		fsys := httpfs.New(base)
+
+		_, err := fs.ReadDir(fsys, "releases")
+
+		Expect(err).To(MatchError(ihfs.ErrNotImplemented))
+	})
+
+	It("should list directories with an index parser", func() {
+		fsys := httpfs.New(base, httpfs.WithIndex(httpfs.HTMLIndex))
+
+		entries, err := fs.ReadDir(fsys, ".")
+
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		Expect(names).To(Equal([]string{"README.md", "releases", "with space.txt"}))
+		Expect(entries[1].IsDir()).To(BeTrue())
+	})
+
+	It("should glob with an index parser", func() {
+		fsys := httpfs.New(base, httpfs.WithIndex(httpfs.HTMLIndex))
+
+		matches, err := fs.Glob(fsys, "releases/*/app.tgz")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(matches).To(Equal([]string{"releases/v1/app.tgz", "releases/v2/app.tgz"}))
+	})
+
+	It("should pass fstest.TestFS", func() {
+		fsys := httpfs.New(base, httpfs.WithIndex(httpfs.HTMLIndex))
+
+		Expect(fstest.TestFS(fsys,
+			"README.md",
+			"releases/v1/app.tgz",
+			"releases/v2/app.tgz",
+			"with space.txt",
+		)).To(Succeed())
+	})
+})
@@ -0,0 +1,13 @@
+package httpfs_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestHttpfs(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Httpfs Suite")
+}
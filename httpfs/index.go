@@ -0,0 +1,42 @@
+package httpfs
+
+import (
+	"html"
+	"io"
+	"net/url"
+	"regexp"
+)
+
+// IndexParser parses the body of a directory listing into the names of its entries.
+// Names of subdirectories end with a slash. Names may be path escaped, and names that are
+// not direct children of the directory, such as "../", are ignored.
+type IndexParser func(r io.Reader) ([]string, error)
+
+var href = regexp.MustCompile(`(?i)<a\s[^>]*href\s*=\s*["']([^"']+)["']`)
+
+// HTMLIndex parses the links of an HTML directory listing, as generated by e.g. nginx autoindex,
+// Apache mod_autoindex, [http.FileServer] or Python's http.server. Links with a query, fragment
+// or host, such as the sorting links of Apache, are ignored.
+func HTMLIndex(r io.Reader) ([]string, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		names []string
+		seen  = map[string]bool{}
+	)
+	for _, m := range href.FindAllSubmatch(body, -1) {
+		u, err := url.Parse(html.UnescapeString(string(m[1])))
+		if err != nil || u.IsAbs() || u.Host != "" || u.RawQuery != "" || u.Fragment != "" {
+			continue
+		}
+		name := u.EscapedPath()
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,39 @@
+package httpfs_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/unstoppablemango/ihfs/httpfs"
+)
+
+var _ = Describe("HTMLIndex", func() {
+	It("should parse nginx autoindex listings", func() {
+		names, err := httpfs.HTMLIndex(strings.NewReader(`<html>
+<head><title>Index of /pub/</title></head>
+<body>
+<h1>Index of /pub/</h1><hr><pre><a href="../">../</a>
+<a href="releases/">releases/</a>                                          01-Jun-2024 12:00       -
+<a href="app%20v1.tgz">app v1.tgz</a>                                      01-Jun-2024 12:00    1024
+</pre><hr></body>
+</html>`))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names).To(Equal([]string{"../", "releases/", "app%20v1.tgz"}))
+	})
+
+	It("should ignore sorting links and absolute links", func() {
+		names, err := httpfs.HTMLIndex(strings.NewReader(`
+<a href="?C=N;O=D">Name</a>
+<a href="/pub/">Parent Directory</a>
+<a href="https://example.com/">Home</a>
+<a href="#top">Top</a>
+<a HREF='file.txt'>file.txt</a>
+<a href="file.txt">file.txt</a>`))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names).To(Equal([]string{"/pub/", "file.txt"}))
+	})
+})
@@ -0,0 +1,20 @@
+package httpfs
+
+import "net/http"
+
+// Option configures an httpfs [Fs].
+type Option func(*Fs)
+
+// WithClient sets the HTTP client used for requests. The default is [http.DefaultClient].
+func WithClient(client *http.Client) Option {
+	return func(f *Fs) {
+		f.client = client
+	}
+}
+
+// WithIndex enables directory listings, parsing the responses for directory URLs with parse.
+func WithIndex(parse IndexParser) Option {
+	return func(f *Fs) {
+		f.index = parse
+	}
+}
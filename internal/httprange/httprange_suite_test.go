@@ -0,0 +1,13 @@
+package httprange_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestHttprange(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Httprange Suite")
+}
@@ -0,0 +1,200 @@
+// Package httprange reads HTTP resources at arbitrary offsets with Range requests.
+// It is shared by the file systems that serve files over HTTP, such as httpfs and ghfs.
+package httprange
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// readAhead is the minimum number of bytes requested by each ReadAt,
+// so that small reads, e.g. of zip headers, do not each cost a request.
+const readAhead = 64 << 10
+
+var (
+	// ErrNegativeOffset is returned for reads before the start of a resource.
+	ErrNegativeOffset = errors.New("negative offset")
+
+	// ErrNotSatisfiable is wrapped by the errors [Fetch] returns for responses
+	// with the status 416 Range Not Satisfiable.
+	ErrNotSatisfiable = errors.New("range not satisfiable")
+
+	// ErrUnknownSize is returned by [Reader.Size] when the server does not reveal the size.
+	ErrUnknownSize = errors.New("unknown size")
+)
+
+// Fetch requests a resource with the given Range header, such as "bytes=0-99" or "bytes=100-".
+// It returns the response for 2xx statuses, and an error wrapping [ErrNotSatisfiable] when the
+// server answers 416 Range Not Satisfiable.
+type Fetch func(rng string) (*http.Response, error)
+
+// Reader reads a resource at arbitrary offsets. ReadAt fetches at least a small read-ahead buffer
+// with each request, and keeps the last one to serve reads that fall within it. Stream reads the
+// rest of the resource from an offset with a single request, for sequential reads after a seek.
+// Servers that ignore Range are read from the start. A Reader is safe for concurrent use.
+type Reader struct {
+	fetch Fetch
+
+	mu     sync.Mutex
+	size   int64 // -1 until known
+	buf    []byte
+	bufOff int64
+}
+
+// New returns a Reader of the resource fetched by fetch. size is the size of
+// the resource, or -1 if it is not known yet.
+func New(fetch Fetch, size int64) *Reader {
+	return &Reader{fetch: fetch, size: size}
+}
+
+// ReadAt implements [io.ReaderAt].
+func (r *Reader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrNegativeOffset
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for n < len(p) && (r.size < 0 || off < r.size) {
+		if off < r.bufOff || off >= r.bufOff+int64(len(r.buf)) {
+			if err := r.fill(off, int64(len(p)-n)); err != nil {
+				return n, err
+			}
+			if len(r.buf) == 0 {
+				break
+			}
+		}
+		c := copy(p[n:], r.buf[off-r.bufOff:])
+		n += c
+		off += int64(c)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Size returns the size of the resource, requesting the start of it if the size is not known yet.
+func (r *Reader) Size() (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.size < 0 {
+		if err := r.fill(0, 1); err != nil {
+			return 0, err
+		}
+	}
+	if r.size < 0 {
+		return 0, ErrUnknownSize
+	}
+	return r.size, nil
+}
+
+// Stream returns the resource from off to its end, read with a single request.
+// The caller must close it.
+func (r *Reader) Stream(off int64) (io.ReadCloser, error) {
+	if off < 0 {
+		return nil, ErrNegativeOffset
+	}
+	r.mu.Lock()
+	size := r.size
+	r.mu.Unlock()
+	if size >= 0 && off >= size {
+		return http.NoBody, nil
+	}
+
+	resp, err := r.fetch(fmt.Sprintf("bytes=%d-", off))
+	if errors.Is(err, ErrNotSatisfiable) {
+		return http.NoBody, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusPartialContent {
+		r.mu.Lock()
+		r.size = contentRangeSize(resp.Header.Get("Content-Range"), r.size)
+		r.mu.Unlock()
+		return resp.Body, nil
+	}
+
+	// Servers that ignore Range send the whole resource.
+	if resp.ContentLength >= 0 {
+		r.mu.Lock()
+		r.size = resp.ContentLength
+		r.mu.Unlock()
+	}
+	if _, err := io.CopyN(io.Discard, resp.Body, off); err != nil && err != io.EOF {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// fill replaces the buffer with at least length bytes starting at off, or up to the end of the
+// resource, and records its size if the response reveals it. The buffer is empty if off is past
+// the end. r.mu must be held.
+func (r *Reader) fill(off, length int64) error {
+	end := off + max(length, readAhead) - 1
+	if r.size >= 0 {
+		end = min(end, r.size-1)
+	}
+
+	r.buf, r.bufOff = nil, off
+	resp, err := r.fetch(fmt.Sprintf("bytes=%d-%d", off, end))
+	if errors.Is(err, ErrNotSatisfiable) {
+		if off == 0 {
+			// Only an empty resource has no byte at offset 0.
+			r.size = 0
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusPartialContent {
+		r.size = contentRangeSize(resp.Header.Get("Content-Range"), r.size)
+	} else {
+		// Servers that ignore Range send the whole resource.
+		if resp.ContentLength >= 0 {
+			r.size = resp.ContentLength
+		}
+		if skipped, err := io.CopyN(io.Discard, resp.Body, off); err == io.EOF {
+			r.size = skipped
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+
+	want := end - off + 1
+	if r.buf, err = io.ReadAll(io.LimitReader(resp.Body, want)); err != nil {
+		return err
+	}
+	if int64(len(r.buf)) < want {
+		r.size = off + int64(len(r.buf))
+	}
+	return nil
+}
+
+// contentRangeSize returns the complete length from a Content-Range header such as
+// "bytes 0-99/1234", or size if the header does not include it.
+func contentRangeSize(header string, size int64) int64 {
+	_, total, ok := strings.Cut(header, "/")
+	if !ok {
+		return size
+	}
+	if n, err := strconv.ParseInt(total, 10, 64); err == nil {
+		return n
+	}
+	return size
+}
@@ -0,0 +1,222 @@
+package httprange_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/unstoppablemango/ihfs/internal/httprange"
+)
+
+var _ = Describe("Reader", func() {
+	var (
+		content []byte
+		mu      sync.Mutex
+		ranges  []string
+	)
+
+	// fetch serves content with http.ServeContent, recording the Range of each request.
+	fetch := func(rng string) (*http.Response, error) {
+		mu.Lock()
+		ranges = append(ranges, rng)
+		mu.Unlock()
+
+		req := httptest.NewRequest(http.MethodGet, "/content", nil)
+		req.Header.Set("Range", rng)
+		w := httptest.NewRecorder()
+		http.ServeContent(w, req, "content", time.Time{}, bytes.NewReader(content))
+		resp := w.Result()
+		if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			return nil, httprange.ErrNotSatisfiable
+		}
+		return resp, nil
+	}
+
+	BeforeEach(func() {
+		content = []byte(strings.Repeat("0123456789", 10<<10))
+		ranges = nil
+	})
+
+	Describe("ReadAt", func() {
+		It("should read at an offset", func() {
+			r := httprange.New(fetch, int64(len(content)))
+
+			p := make([]byte, 4)
+			n, err := r.ReadAt(p, 3)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(4))
+			Expect(string(p)).To(Equal("3456"))
+		})
+
+		It("should read ahead of small reads", func() {
+			r := httprange.New(fetch, int64(len(content)))
+
+			for off := range int64(100) {
+				_, err := r.ReadAt(make([]byte, 10), off*10)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(ranges).To(Equal([]string{"bytes=0-65535"}))
+		})
+
+		It("should read across the read-ahead buffer", func() {
+			r := httprange.New(fetch, int64(len(content)))
+
+			p := make([]byte, len(content)-10)
+			n, err := r.ReadAt(p, 10)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(len(content) - 10))
+			Expect(p).To(Equal(content[10:]))
+		})
+
+		It("should return io.EOF at the end", func() {
+			r := httprange.New(fetch, -1)
+
+			p := make([]byte, 10)
+			n, err := r.ReadAt(p, int64(len(content)-4))
+
+			Expect(err).To(MatchError(io.EOF))
+			Expect(string(p[:n])).To(Equal("6789"))
+		})
+
+		It("should return io.EOF past the end", func() {
+			r := httprange.New(fetch, -1)
+
+			n, err := r.ReadAt(make([]byte, 10), int64(len(content)+10))
+
+			Expect(err).To(MatchError(io.EOF))
+			Expect(n).To(BeZero())
+		})
+
+		It("should reject negative offsets", func() {
+			r := httprange.New(fetch, -1)
+
+			_, err := r.ReadAt(make([]byte, 1), -1)
+
+			Expect(err).To(MatchError(httprange.ErrNegativeOffset))
+		})
+
+		It("should read from servers that ignore Range", func() {
+			r := httprange.New(func(string) (*http.Response, error) {
+				return &http.Response{
+					StatusCode:    http.StatusOK,
+					ContentLength: 10,
+					Body:          io.NopCloser(strings.NewReader("0123456789")),
+				}, nil
+			}, -1)
+
+			p := make([]byte, 3)
+			n, err := r.ReadAt(p, 5)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(p[:n])).To(Equal("567"))
+			Expect(r.Size()).To(Equal(int64(10)))
+		})
+
+		It("should be safe for concurrent use", func() {
+			r := httprange.New(fetch, -1)
+
+			var wg sync.WaitGroup
+			for i := range 8 {
+				wg.Go(func() {
+					defer GinkgoRecover()
+					off := int64(i * 10 << 10)
+					p := make([]byte, 10)
+					_, err := r.ReadAt(p, off)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(p)).To(Equal("0123456789"))
+				})
+			}
+			wg.Wait()
+
+			Expect(r.Size()).To(Equal(int64(len(content))))
+		})
+	})
+
+	Describe("Size", func() {
+		It("should request the size when it is not known", func() {
+			r := httprange.New(fetch, -1)
+
+			Expect(r.Size()).To(Equal(int64(len(content))))
+			Expect(ranges).To(Equal([]string{"bytes=0-65535"}))
+		})
+
+		It("should return known sizes without requests", func() {
+			r := httprange.New(fetch, 42)
+
+			Expect(r.Size()).To(Equal(int64(42)))
+			Expect(ranges).To(BeEmpty())
+		})
+
+		It("should find empty resources", func() {
+			content = nil
+			r := httprange.New(fetch, -1)
+
+			Expect(r.Size()).To(BeZero())
+		})
+	})
+
+	Describe("Stream", func() {
+		It("should read the rest of the resource with a single request", func() {
+			r := httprange.New(fetch, -1)
+
+			rc, err := r.Stream(5)
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(rc.Close)
+			data, err := io.ReadAll(rc)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(Equal(content[5:]))
+			Expect(ranges).To(Equal([]string{"bytes=5-"}))
+			Expect(r.Size()).To(Equal(int64(len(content))))
+		})
+
+		It("should be empty past the end", func() {
+			r := httprange.New(fetch, -1)
+
+			rc, err := r.Stream(int64(len(content)))
+			Expect(err).NotTo(HaveOccurred())
+			data, err := io.ReadAll(rc)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(BeEmpty())
+		})
+
+		It("should skip to the offset on servers that ignore Range", func() {
+			r := httprange.New(func(string) (*http.Response, error) {
+				return &http.Response{
+					StatusCode:    http.StatusOK,
+					ContentLength: -1,
+					Body:          io.NopCloser(strings.NewReader("0123456789")),
+				}, nil
+			}, -1)
+
+			rc, err := r.Stream(7)
+			Expect(err).NotTo(HaveOccurred())
+			data, err := io.ReadAll(rc)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("789"))
+		})
+
+		It("should return fetch errors", func() {
+			r := httprange.New(func(string) (*http.Response, error) {
+				return nil, fmt.Errorf("offline")
+			}, -1)
+
+			_, err := r.Stream(0)
+
+			Expect(err).To(MatchError("offline"))
+		})
+	})
+})
@@ -36,6 +36,20 @@ type FileData struct {
 	gid     int
 }
 
+// Uid returns the numeric user id of the file's owner.
+func (fd *FileData) Uid() int {
+	fd.Lock()
+	defer fd.Unlock()
+	return fd.uid
+}
+
+// Gid returns the numeric group id of the file's owner.
+func (fd *FileData) Gid() int {
+	fd.Lock()
+	defer fd.Unlock()
+	return fd.gid
+}
+
 func (fd *FileData) error(op string, err error) error {
 	return &ihfs.PathError{
 		Op:   op,
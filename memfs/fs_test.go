@@ -362,6 +362,13 @@ var _ = Describe("Fs", func() {
 
 			err = mfs.Chown("/test.txt", 1000, 1000)
 			Expect(err).NotTo(HaveOccurred())
+
+			fi, err := mfs.Stat("/test.txt")
+			Expect(err).NotTo(HaveOccurred())
+			data, ok := fi.Sys().(*memfs.FileData)
+			Expect(ok).To(BeTrue())
+			Expect(data.Uid()).To(Equal(1000))
+			Expect(data.Gid()).To(Equal(1000))
 		})
 	})
 
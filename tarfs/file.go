@@ -109,7 +109,8 @@ func (f *File) buildDirSnapshot() []fs.DirEntry {
 		parts := strings.SplitN(rel, "/", 2)
 		baseName := parts[0]
 
-		if baseName == "" || seen[baseName] {
+		// The archive root may have its own "." entry, which is not a child of any directory.
+		if baseName == "" || baseName == "." || seen[baseName] {
 			continue
 		}
 		seen[baseName] = true
@@ -527,6 +527,33 @@ var _ = Describe("Fs", func() {
 			Expect(entries[0].Name()).To(Equal("file.txt"))
 		})
 
+		It("should not list the archive root as a child of itself", func() {
+			var buf bytes.Buffer
+			tw := tar.NewWriter(&buf)
+
+			Expect(tw.WriteHeader(&tar.Header{
+				Name:     "./",
+				Typeflag: tar.TypeDir,
+				Mode:     0755,
+			})).To(Succeed())
+			content := []byte("content")
+			Expect(tw.WriteHeader(&tar.Header{
+				Name: "file.txt",
+				Mode: 0644,
+				Size: int64(len(content)),
+			})).To(Succeed())
+			_, err := tw.Write(content)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tw.Close()).To(Succeed())
+
+			tfs := tarfs.FromReader("root.tar", &buf)
+
+			entries, err := fs.ReadDir(tfs, ".")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Name()).To(Equal("file.txt"))
+		})
+
 		It("should return error when tar is corrupted after cached directory", func() {
 			// Archive: mydir/ → other.txt → mydir/corrupt.txt (incomplete).
 			// Opening other.txt caches mydir/ as a side-effect; draining for mydir then
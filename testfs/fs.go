@@ -41,6 +41,7 @@ type Fs struct {
 	SubFunc          func(string) (ihfs.FS, error)
 	SymlinkFunc      func(string, string) error
 	TempFileFunc     func(string, string) (string, error)
+	VersionFunc      func(string) (string, error)
 }
 
 // New creates a new test [Fs] with the given options.
@@ -71,6 +72,7 @@ func New(opts ...Option) Fs {
 		SubFunc:          defaultSubFunc,
 		SymlinkFunc:      defaultSymlinkFunc,
 		TempFileFunc:     defaultTempFileFunc,
+		VersionFunc:      defaultVersionFunc,
 	}
 
 	fopt.ApplyAll(&fs, opts)
@@ -301,3 +303,12 @@ func (fs Fs) TempFile(dir, pattern string) (string, error) {
 func defaultTempFileFunc(_, _ string) (string, error) {
 	return "", fs.ErrPermission
 }
+
+// Version implements [ihfs.VersionFS].
+func (fs Fs) Version(name string) (string, error) {
+	return fs.VersionFunc(name)
+}
+
+func defaultVersionFunc(string) (string, error) {
+	return "", fs.ErrPermission
+}
@@ -183,3 +183,10 @@ func WithTempFile(fn func(string, string) (string, error)) Option {
 		fs.TempFileFunc = fn
 	}
 }
+
+// WithVersion sets the Version function on the test filesystem.
+func WithVersion(fn func(string) (string, error)) Option {
+	return func(fs *Fs) {
+		fs.VersionFunc = fn
+	}
+}
@@ -219,6 +219,13 @@ func TempFile(fsys ihfs.FS, dir, pattern string) (string, error) {
 	return ihfs.TempFile(fsys, dir, pattern)
 }
 
+// Version attempts to call Version on the given FS.
+// If the FS does not implement [ihfs.VersionFS], Version returns
+// an error that can be checked with [errors.Is] for [ErrNotImplemented].
+func Version(fsys ihfs.FS, name string) (string, error) {
+	return ihfs.Version(fsys, name)
+}
+
 // WriteFile attempts to call WriteFile on the given FS.
 // If the FS does not implement [ihfs.WriteFileFS], WriteFile returns
 // an error that can be checked with [errors.Is] for [ErrNotImplemented].
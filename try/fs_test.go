@@ -824,6 +824,25 @@ var _ = Describe("Try Util", func() {
 		})
 	})
 
+	Describe("Version", func() {
+		It("should call Version on the filesystem", func() {
+			fsys := testfs.New(testfs.WithVersion(func(name string) (string, error) {
+				return "v-" + name, nil
+			}))
+
+			version, err := try.Version(fsys, "file.txt")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(Equal("v-file.txt"))
+		})
+
+		It("should return ErrNotImplemented when fs does not support Version", func() {
+			_, err := try.Version(testfs.BoringFs{}, "file.txt")
+
+			Expect(err).To(MatchError(try.ErrNotImplemented))
+		})
+	})
+
 	Describe("ReadDirNames with ReadDirNamesFS", func() {
 		It("should call ReadDirNames on ReadDirNamesFS when supported", func() {
 			var capturedName string
@@ -27,6 +27,14 @@ import (
 //   - The copy operation fails
 //   - File metadata cannot be retrieved or set
 func CopyToLayer(base, layer ihfs.FS, name string) error {
+	return CopyToLayerAs(base, layer, name, name)
+}
+
+// CopyToLayerAs copies the file name from the base filesystem to target in the
+// layer filesystem, in the same way as [CopyToLayer]. Copying to a temporary
+// target and renaming it afterwards keeps readers of the layer from seeing a
+// partial copy.
+func CopyToLayerAs(base, layer ihfs.FS, name, target string) error {
 	// TODO: Check for ihfs.CopyFS interface and use that if available
 	file, err := base.Open(name)
 	if err != nil {
@@ -34,7 +42,7 @@ func CopyToLayer(base, layer ihfs.FS, name string) error {
 	}
 	defer func() { _ = file.Close() }()
 
-	return copyFile(layer, name, file)
+	return copyFile(layer, target, file)
 }
 
 // copyFile is an internal helper that performs the actual file copy operation.
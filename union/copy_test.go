@@ -510,3 +510,57 @@ var _ = Describe("CopyToLayer", func() {
 		})
 	})
 })
+
+var _ = Describe("CopyToLayerAs", func() {
+	It("should copy the file to the target", func() {
+		modTime := time.Now().Add(-time.Hour)
+		base := testfs.New(
+			testfs.WithOpen(func(name string) (ihfs.File, error) {
+				Expect(name).To(Equal("dir/test.txt"))
+				return &testfs.File{
+					ReadFunc: func(p []byte) (int, error) {
+						return copy(p, "content"), io.EOF
+					},
+					StatFunc: func() (ihfs.FileInfo, error) {
+						fi := testfs.NewFileInfo("test.txt")
+						fi.SizeFunc = func() int64 { return 7 }
+						fi.ModTimeFunc = func() time.Time { return modTime }
+						return fi, nil
+					},
+					CloseFunc: func() error { return nil },
+				}, nil
+			}),
+		)
+
+		var created, chtimed string
+		var copied []byte
+		layer := testfs.New(
+			testfs.WithStat(func(name string) (ihfs.FileInfo, error) {
+				fi := testfs.NewFileInfo(name)
+				fi.IsDirFunc = func() bool { return true }
+				return fi, nil
+			}),
+			testfs.WithCreate(func(name string) (ihfs.File, error) {
+				created = name
+				return &testfs.File{
+					WriteFunc: func(p []byte) (int, error) {
+						copied = append(copied, p...)
+						return len(p), nil
+					},
+					CloseFunc: func() error { return nil },
+				}, nil
+			}),
+			testfs.WithChtimes(func(name string, _, _ time.Time) error {
+				chtimed = name
+				return nil
+			}),
+		)
+
+		err := union.CopyToLayerAs(base, layer, "dir/test.txt", "dir/.test.txt.tmp")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(created).To(Equal("dir/.test.txt.tmp"))
+		Expect(chtimed).To(Equal("dir/.test.txt.tmp"))
+		Expect(string(copied)).To(Equal("content"))
+	})
+})
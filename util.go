@@ -403,6 +403,18 @@ func TempFile(fsys FS, dir, pattern string) (string, error) {
 	return "", fmt.Errorf("temp file: %w", ErrNotImplemented)
 }
 
+// Version returns an opaque version of the named file in fsys.
+//
+// If fsys implements [VersionFS], Version calls fsys.Version.
+// Otherwise, Version returns an error that can be checked
+// with [errors.Is] for [ErrNotImplemented].
+func Version(fsys FS, name string) (string, error) {
+	if version, ok := fsys.(VersionFS); ok {
+		return version.Version(name)
+	}
+	return "", fmt.Errorf("version: %w", ErrNotImplemented)
+}
+
 // WriteReader reads all data from r and writes it to name in fsys using [WriteFile].
 // It returns an error if reading from r fails or if [WriteFile] reports an error.
 func WriteReader(fsys FS, name string, r io.Reader, perm FileMode) error {
@@ -1162,6 +1162,26 @@ var _ = Describe("Util", func() {
 			Expect(name).To(BeEmpty())
 		})
 	})
+
+	Describe("Version", func() {
+		It("should call underlying Version when VersionFS is implemented", func() {
+			fsys := testfs.New(testfs.WithVersion(func(name string) (string, error) {
+				return "v-" + name, nil
+			}))
+
+			version, err := ihfs.Version(fsys, "file.txt")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(Equal("v-file.txt"))
+		})
+
+		It("should return ErrNotImplemented when VersionFS not implemented", func() {
+			version, err := ihfs.Version(testfs.BoringFs{}, "file.txt")
+
+			Expect(err).To(MatchError(ihfs.ErrNotImplemented))
+			Expect(version).To(BeEmpty())
+		})
+	})
 })
 
 type errorReader struct {